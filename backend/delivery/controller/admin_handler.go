@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	adminUsecase usecase.AdminUsecase
+}
+
+func NewAdminHandler(adminUsecase usecase.AdminUsecase) *AdminHandler {
+	return &AdminHandler{
+		adminUsecase: adminUsecase,
+	}
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	filter := entities.UserFilter{
+		Query:  c.Query("q"),
+		Role:   c.Query("role"),
+		Status: c.Query("status"),
+	}
+
+	users, err := h.adminUsecase.ListUsers(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": users})
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, err := h.adminUsecase.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+func (h *AdminHandler) SetUserRole(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.adminUsecase.SetUserRole(c.Request.Context(), actorID.(string), c.Param("id"), req.Role)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+func (h *AdminHandler) DeactivateUser(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.adminUsecase.DeactivateUser(c.Request.Context(), actorID.(string), c.Param("id")); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deactivated"})
+}
+
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	if err := h.adminUsecase.ReactivateUser(c.Request.Context(), c.Param("id")); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user reactivated"})
+}
+
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.adminUsecase.DeleteUser(c.Request.Context(), actorID.(string), c.Param("id")); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
+
+func (h *AdminHandler) ListPortfolios(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	portfolios, err := h.adminUsecase.ListPortfolios(c.Request.Context(), c.Query("user_id"), c.Query("q"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": portfolios})
+}
+
+func (h *AdminHandler) UpdatePortfolio(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.UpdatePortfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	portfolio, err := h.adminUsecase.UpdatePortfolio(c.Request.Context(), actorID.(string), c.Param("id"), &req, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": portfolio})
+}
+
+func (h *AdminHandler) DeletePortfolio(c *gin.Context) {
+	if err := h.adminUsecase.DeletePortfolio(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Portfolio deleted successfully"})
+}
+
+// ImpersonateUser returns an access token for acting as the user. Every call is
+// recorded in the audit log with the given reason.
+func (h *AdminHandler) ImpersonateUser(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	impersonation, err := h.adminUsecase.ImpersonateUser(c.Request.Context(), actorID.(string), c.Param("id"), req.Reason, clientInfo(c))
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"data": impersonation})
+}
+
+func adminError(c *gin.Context, err error) {
+	if errors.Is(err, usecase.ErrAdminSelfAction) || errors.Is(err, usecase.ErrImpersonationNotAllowed) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// pageParams reads limit and offset, writing a 400 response if either is not a number.
+func pageParams(c *gin.Context) (int, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return 0, 0, false
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return 0, 0, false
+	}
+
+	return limit, offset, true
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditUsecase usecase.AuditUsecase
+}
+
+func NewAuditHandler(auditUsecase usecase.AuditUsecase) *AuditHandler {
+	return &AuditHandler{
+		auditUsecase: auditUsecase,
+	}
+}
+
+// ListActivity returns the signed-in user's own audit events, newest first.
+func (h *AuditHandler) ListActivity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.auditUsecase.ListActivity(c.Request.Context(), userID.(string), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": events})
+}
+
+// ListEvents searches the whole audit log. since and until are RFC 3339 times.
+func (h *AuditHandler) ListEvents(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	filter := entities.AuditEventFilter{
+		UserID:    c.Query("user_id"),
+		Type:      c.Query("type"),
+		Email:     c.Query("email"),
+		IPAddress: c.Query("ip"),
+	}
+	if filter.Since, ok = timeParam(c, "since"); !ok {
+		return
+	}
+	if filter.Until, ok = timeParam(c, "until"); !ok {
+		return
+	}
+
+	events, err := h.auditUsecase.ListEvents(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": events})
+}
+
+// timeParam reads an optional RFC 3339 query parameter, writing a 400 response
+// if it is malformed.
+func timeParam(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " parameter"})
+		return nil, false
+	}
+
+	return &parsed, true
+}
@@ -1,29 +1,39 @@
 package controller
 
 import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"devfolio-backend/domain/entities"
 	"devfolio-backend/infrastructure/auth"
 	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/middleware"
 	"devfolio-backend/usecase"
 	
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	authUsecase usecase.AuthUsecase
-	config      *config.Config
-	googleAuth  *auth.GoogleOAuthManager
+	authUsecase    usecase.AuthUsecase
+	accountUsecase usecase.AccountUsecase
+	config         *config.Config
+	oauth          *auth.OAuthRegistry
 }
 
-func NewAuthHandler(authUsecase usecase.AuthUsecase, config *config.Config) *AuthHandler {
+func NewAuthHandler(authUsecase usecase.AuthUsecase, accountUsecase usecase.AccountUsecase, oauth *auth.OAuthRegistry, config *config.Config) *AuthHandler {
 	return &AuthHandler{
-		authUsecase: authUsecase,
-		config:      config,
-		googleAuth:  auth.NewGoogleOAuthManager(config),
+		authUsecase:    authUsecase,
+		accountUsecase: accountUsecase,
+		config:         config,
+		oauth:          oauth,
 	}
 }
 
@@ -34,9 +44,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, tokens, err := h.authUsecase.Register(c.Request.Context(), &req)
+	user, tokens, err := h.authUsecase.Register(c.Request.Context(), &req, clientInfo(c))
+	if registrationRefused(err) || errors.Is(err, usecase.ErrAccountDeactivated) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, passwordErrorResponse(err))
 		return
 	}
 
@@ -58,7 +72,22 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, tokens, err := h.authUsecase.Login(c.Request.Context(), &req)
+	user, tokens, err := h.authUsecase.Login(c.Request.Context(), &req, clientInfo(c))
+	var mfaErr *usecase.MFARequiredError
+	if errors.As(err, &mfaErr) {
+		c.JSON(http.StatusOK, gin.H{"data": entities.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaErr.Token,
+		}})
+		return
+	}
+	if abortIfThrottled(c, err) {
+		return
+	}
+	if errors.Is(err, usecase.ErrPasswordResetRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "password_reset_required": true})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -75,6 +104,54 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": response})
 }
 
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req entities.LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, tokens, err := h.authUsecase.LoginMFA(c.Request.Context(), &req, clientInfo(c))
+	if abortIfThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setRefreshTokenCookie(c, tokens.RefreshToken)
+
+	response := entities.LoginResponse{
+		User:        user,
+		AccessToken: tokens.AccessToken,
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// CSRFToken returns the CSRF token to send with cookie-authenticated requests,
+// for frontends that cannot read the API's cookies. Sessions started before
+// CSRF tokens were issued get one here.
+func (h *AuthHandler) CSRFToken(c *gin.Context) {
+	if _, err := c.Cookie("refresh_token"); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token not found"})
+		return
+	}
+
+	token, err := c.Cookie(middleware.CSRFCookieName)
+	if err != nil || token == "" {
+		refreshTTL, _ := time.ParseDuration(h.config.JWT.RefreshExpiry)
+		if token = h.setCSRFCookie(c, int(refreshTTL.Seconds())); token == "" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue CSRF token"})
+			return
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"csrf_token": token}})
+}
+
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
@@ -82,7 +159,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	accessToken, err := h.authUsecase.RefreshToken(c.Request.Context(), refreshToken)
+	tokens, err := h.authUsecase.RefreshToken(c.Request.Context(), refreshToken, clientInfo(c))
 	if err != nil {
 		// Clear invalid refresh token cookie
 		h.clearRefreshTokenCookie(c)
@@ -90,71 +167,124 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// The refresh token is rotated on every use
+	h.setRefreshTokenCookie(c, tokens.RefreshToken)
+
 	response := entities.RefreshTokenResponse{
-		AccessToken: accessToken,
+		AccessToken: tokens.AccessToken,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": response})
 }
 
-func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	if !h.googleAuth.IsConfigured() {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Google login is not configured yet."))
+// ListOAuthProviders returns the external login providers that are configured.
+func (h *AuthHandler) ListOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.oauth.Names()})
+}
+
+func (h *AuthHandler) OAuthLogin(c *gin.Context) {
+	name := c.Param("provider")
+	provider, ok := h.oauth.Get(name)
+	if !ok {
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "This login provider is not configured."))
 		return
 	}
 
-	state, err := h.googleAuth.RandomState()
+	flow, err := auth.NewOAuthFlow()
 	if err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Failed to initialize Google login."))
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Failed to initialize login."))
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), flow)
+	if err != nil {
+		log.Printf("Failed to start %s login: %v", provider.Name(), err)
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Failed to initialize login."))
 		return
 	}
 
 	h.applySameSite(c)
-	c.SetCookie("google_oauth_state", state, 600, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, true)
-	c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.BuildAuthorizationURL(state))
+	c.SetCookie(oauthStateCookie(provider), flow.Encode(), 600, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, true)
+	// Kept until the callback in case the login creates an account
+	if inviteCode := c.Query("invite_code"); inviteCode != "" {
+		c.SetCookie(oauthInviteCookie(provider), inviteCode, 600, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, true)
+	}
+	c.Redirect(http.StatusTemporaryRedirect, authURL)
 }
 
-func (h *AuthHandler) GoogleCallback(c *gin.Context) {
-	if !h.googleAuth.IsConfigured() {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Google login is not configured yet."))
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	name := c.Param("provider")
+	provider, ok := h.oauth.Get(name)
+	if !ok {
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "This login provider is not configured."))
 		return
 	}
 
 	if callbackError := c.Query("error"); callbackError != "" {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Google authentication was canceled."))
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Authentication was canceled."))
 		return
 	}
 
 	code := c.Query("code")
 	state := c.Query("state")
 	if code == "" || state == "" {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Missing Google callback parameters."))
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Missing callback parameters."))
 		return
 	}
 
-	cookieState, err := c.Cookie("google_oauth_state")
-	if err != nil || cookieState == "" || cookieState != state {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Google login state could not be verified."))
+	cookieValue, err := c.Cookie(oauthStateCookie(provider))
+	if err != nil {
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Login state could not be verified."))
+		return
+	}
+	flow, err := auth.ParseOAuthFlow(cookieValue)
+	if err != nil || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Login state could not be verified."))
 		return
 	}
+	// The state cookie is single use
+	h.clearOAuthStateCookie(c, provider)
 
-	profile, err := h.googleAuth.ExchangeCode(c.Request.Context(), code)
+	profile, err := provider.Exchange(c.Request.Context(), code, flow)
 	if err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Failed to complete Google login."))
+		log.Printf("Failed to complete %s login: %v", provider.Name(), err)
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Failed to complete login."))
+		return
+	}
+
+	linked, err := h.authUsecase.FinishIdentityLink(c.Request.Context(), flow.State, profile)
+	if linked {
+		if err != nil {
+			c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, err.Error()))
+			return
+		}
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendStatusURL(name, "linked", ""))
 		return
 	}
 
-	user, tokens, err := h.authUsecase.LoginWithGoogle(c.Request.Context(), profile)
+	inviteCode, _ := c.Cookie(oauthInviteCookie(provider))
+	if inviteCode != "" {
+		c.SetCookie(oauthInviteCookie(provider), "", -1, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, true)
+	}
+
+	_, tokens, err := h.authUsecase.LoginWithProvider(c.Request.Context(), profile, inviteCode, clientInfo(c))
+	var linkErr *usecase.LinkRequiredError
+	if errors.As(err, &linkErr) {
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendStatusURL(name, "link_required", linkErr.Error()))
+		return
+	}
+	if registrationRefused(err) || errors.Is(err, usecase.ErrAccountDeactivated) {
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, err.Error()))
+		return
+	}
 	if err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(false, "Failed to create or load your account."))
+		c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, false, "Failed to create or load your account."))
 		return
 	}
 
-	h.clearGoogleStateCookie(c)
 	h.setRefreshTokenCookie(c, tokens.RefreshToken)
-	_ = user
 
-	c.Redirect(http.StatusTemporaryRedirect, h.googleAuth.FrontendCallbackURL(true, ""))
+	c.Redirect(http.StatusTemporaryRedirect, h.oauth.FrontendCallbackURL(name, true, ""))
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
@@ -164,7 +294,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	if err := h.authUsecase.Logout(c.Request.Context(), userID.(string)); err != nil {
+	if err := h.authUsecase.Logout(c.Request.Context(), userID.(string), c.GetString("session_id"), clientInfo(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -175,6 +305,39 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
 }
 
+// DeleteAccount deactivates the account and schedules its data for deletion.
+// The body can be left out by accounts without a password or second factor.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletion, err := h.accountUsecase.DeleteAccount(c.Request.Context(), userID.(string), c.GetString("session_id"), &req, clientInfo(c))
+	if err != nil {
+		if abortIfThrottled(c, err) {
+			return
+		}
+		if errors.Is(err, usecase.ErrReauthenticationRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "reauthentication_required": true})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.clearRefreshTokenCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{"data": deletion})
+}
+
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -204,7 +367,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authUsecase.UpdateProfile(c.Request.Context(), userID.(string), &req)
+	user, err := h.authUsecase.UpdateProfile(c.Request.Context(), userID.(string), &req, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -226,14 +389,496 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.authUsecase.ChangePassword(c.Request.Context(), userID.(string), &req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.authUsecase.ChangePassword(c.Request.Context(), userID.(string), &req, clientInfo(c)); err != nil {
+		c.JSON(http.StatusBadRequest, passwordErrorResponse(err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
 }
 
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.accountUsecase.RequestEmailChange(c.Request.Context(), userID.(string), c.GetString("session_id"), &req, clientInfo(c)); err != nil {
+		if abortIfThrottled(c, err) {
+			return
+		}
+		if errors.Is(err, usecase.ErrReauthenticationRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "reauthentication_required": true})
+			return
+		}
+		if registrationRefused(err) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "a confirmation link has been sent to the new email address"})
+}
+
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	var req entities.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.accountUsecase.ConfirmEmailChange(c.Request.Context(), req.Token, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user.ToResponse()})
+}
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req entities.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authUsecase.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user.ToResponse()})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authUsecase.ResendVerification(c.Request.Context(), userID.(string)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var req entities.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.authUsecase.RequestMagicLink(c.Request.Context(), &req, clientInfo(c))
+	if abortIfThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Magic link request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send sign-in link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "a sign-in link has been sent to your email"})
+}
+
+// ConfirmMagicLink is opened from the emailed link. It only asks the user to
+// confirm, so a scanner following the link does not use it up.
+func (h *AuthHandler) ConfirmMagicLink(c *gin.Context) {
+	renderConfirmation(c, confirmation{
+		Title:   "Sign in to DevFolio",
+		Message: "Continue to sign in with this link. If you do not have an account yet, one will be created for your address.",
+		Button:  "Sign in",
+		Fields:  map[string]string{"token": c.Query("token"), "invite_code": c.Query("invite_code")},
+	})
+}
+
+// MagicLinkCallback receives the confirmation form. Like a provider callback it
+// sets the refresh cookie and sends the browser on to the frontend.
+func (h *AuthHandler) MagicLinkCallback(c *gin.Context) {
+	_, tokens, err := h.authUsecase.LoginWithMagicLink(c.Request.Context(), c.PostForm("token"), c.PostForm("invite_code"), clientInfo(c))
+	var mfaErr *usecase.MFARequiredError
+	if errors.As(err, &mfaErr) {
+		redirect := h.oauth.FrontendStatusURL("magic-link", "mfa_required", "") + "&mfa_token=" + url.QueryEscape(mfaErr.Token)
+		c.Redirect(http.StatusSeeOther, redirect)
+		return
+	}
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, h.oauth.FrontendCallbackURL("magic-link", false, err.Error()))
+		return
+	}
+
+	h.setRefreshTokenCookie(c, tokens.RefreshToken)
+
+	c.Redirect(http.StatusSeeOther, h.oauth.FrontendCallbackURL("magic-link", true, ""))
+}
+
+// ConfirmSecureAccount is opened from the "this wasn't me" link in a new device
+// email. Locking the account down waits for the user to confirm, so a scanner
+// following the link cannot sign everyone out.
+func (h *AuthHandler) ConfirmSecureAccount(c *gin.Context) {
+	renderConfirmation(c, confirmation{
+		Title:   "Secure your DevFolio account",
+		Message: "This signs out every session. If your account has a password, it stops working until you reset it with the link we will email you.",
+		Button:  "Secure my account",
+		Fields:  map[string]string{"token": c.Query("token")},
+	})
+}
+
+// SecureAccountCallback receives the confirmation form. It locks the account
+// down and sends the browser on to the frontend.
+func (h *AuthHandler) SecureAccountCallback(c *gin.Context) {
+	if err := h.authUsecase.SecureAccount(c.Request.Context(), c.PostForm("token"), clientInfo(c)); err != nil {
+		c.Redirect(http.StatusSeeOther, h.oauth.FrontendCallbackURL("secure-account", false, err.Error()))
+		return
+	}
+
+	// This browser may be the one that was signed in
+	h.clearRefreshTokenCookie(c)
+
+	c.Redirect(http.StatusSeeOther, h.oauth.FrontendCallbackURL("secure-account", true, ""))
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req entities.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUsecase.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		// Still answer the same way so the response never tells whether the email is registered
+		log.Printf("Forgot password request failed: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "if an account exists for that email, a password reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req entities.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUsecase.ResetPassword(c.Request.Context(), &req, clientInfo(c)); err != nil {
+		c.JSON(http.StatusBadRequest, passwordErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+}
+
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	setup, err := h.authUsecase.EnrollTwoFactor(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": setup})
+}
+
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := h.authUsecase.ConfirmTwoFactor(c.Request.Context(), userID.(string), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": entities.RecoveryCodesResponse{RecoveryCodes: recoveryCodes}})
+}
+
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUsecase.DisableTwoFactor(c.Request.Context(), userID.(string), req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+func (h *AuthHandler) BeginPasskeyRegistration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	options, err := h.authUsecase.BeginPasskeyRegistration(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": options})
+}
+
+func (h *AuthHandler) FinishPasskeyRegistration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.PasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passkey, err := h.authUsecase.FinishPasskeyRegistration(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": passkey})
+}
+
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	options, err := h.authUsecase.BeginPasskeyLogin(c.Request.Context(), clientInfo(c))
+	if abortIfThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": options})
+}
+
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	var req entities.PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, tokens, err := h.authUsecase.FinishPasskeyLogin(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setRefreshTokenCookie(c, tokens.RefreshToken)
+
+	response := entities.LoginResponse{
+		User:        user,
+		AccessToken: tokens.AccessToken,
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+func (h *AuthHandler) ListPasskeys(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	passkeys, err := h.authUsecase.ListPasskeys(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": passkeys})
+}
+
+func (h *AuthHandler) DeletePasskey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authUsecase.DeletePasskey(c.Request.Context(), userID.(string), c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrLastCredential) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "passkey deleted successfully"})
+}
+
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	identities, err := h.authUsecase.ListIdentities(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": identities})
+}
+
+// LinkIdentity starts a provider login that links the provider account to the
+// signed-in user. The browser has to visit the returned URL, and the provider
+// then redirects to the usual callback.
+func (h *AuthHandler) LinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	provider, ok := h.oauth.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "login provider not configured"})
+		return
+	}
+
+	flow, err := auth.NewOAuthFlow()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start linking"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), flow)
+	if err != nil {
+		log.Printf("Failed to start %s link: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to start linking"})
+		return
+	}
+
+	if err := h.authUsecase.BeginIdentityLink(c.Request.Context(), userID.(string), flow.State); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.applySameSite(c)
+	c.SetCookie(oauthStateCookie(provider), flow.Encode(), 600, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, true)
+	c.JSON(http.StatusOK, gin.H{"data": entities.OAuthLinkResponse{AuthorizationURL: authURL}})
+}
+
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authUsecase.UnlinkIdentity(c.Request.Context(), userID.(string), c.Param("provider")); err != nil {
+		if errors.Is(err, usecase.ErrLastCredential) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "identity unlinked successfully"})
+}
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessions, err := h.authUsecase.ListSessions(c.Request.Context(), userID.(string), c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessionID := c.Param("id")
+	if err := h.authUsecase.RevokeSession(c.Request.Context(), userID.(string), sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Revoking the session this request belongs to is a logout
+	if sessionID == c.GetString("session_id") {
+		h.clearRefreshTokenCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked successfully"})
+}
+
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authUsecase.RevokeAllSessions(c.Request.Context(), userID.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.clearRefreshTokenCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out of all sessions"})
+}
+
 func (h *AuthHandler) setRefreshTokenCookie(c *gin.Context, refreshToken string) {
 	// Parse refresh token TTL
 	refreshTTL, _ := time.ParseDuration(h.config.JWT.RefreshExpiry)
@@ -248,6 +893,23 @@ func (h *AuthHandler) setRefreshTokenCookie(c *gin.Context, refreshToken string)
 		h.config.Cookie.Secure,    // secure
 		true,                      // httpOnly
 	)
+
+	h.setCSRFCookie(c, int(refreshTTL.Seconds()))
+}
+
+// setCSRFCookie issues a new CSRF token with the refresh cookie. Frontends on
+// the same site can read the cookie; others use the response header or GET /auth/csrf.
+func (h *AuthHandler) setCSRFCookie(c *gin.Context, maxAge int) string {
+	token, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate CSRF token: %v", err)
+		return ""
+	}
+
+	h.applySameSite(c)
+	c.SetCookie(middleware.CSRFCookieName, token, maxAge, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, false)
+	c.Header(middleware.CSRFHeaderName, token)
+	return token
 }
 
 func (h *AuthHandler) clearRefreshTokenCookie(c *gin.Context) {
@@ -261,12 +923,22 @@ func (h *AuthHandler) clearRefreshTokenCookie(c *gin.Context) {
 		h.config.Cookie.Secure, // secure
 		true,                   // httpOnly
 	)
+
+	c.SetCookie(middleware.CSRFCookieName, "", -1, "/", h.config.Cookie.Domain, h.config.Cookie.Secure, false)
+}
+
+func oauthStateCookie(provider auth.OAuthProvider) string {
+	return provider.Name() + "_oauth_state"
 }
 
-func (h *AuthHandler) clearGoogleStateCookie(c *gin.Context) {
+func oauthInviteCookie(provider auth.OAuthProvider) string {
+	return provider.Name() + "_oauth_invite"
+}
+
+func (h *AuthHandler) clearOAuthStateCookie(c *gin.Context, provider auth.OAuthProvider) {
 	h.applySameSite(c)
 	c.SetCookie(
-		"google_oauth_state",
+		oauthStateCookie(provider),
 		"",
 		-1,
 		"/",
@@ -276,6 +948,48 @@ func (h *AuthHandler) clearGoogleStateCookie(c *gin.Context) {
 	)
 }
 
+func clientInfo(c *gin.Context) *entities.ClientInfo {
+	return &entities.ClientInfo{
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		ImpersonatorID: c.GetString("impersonator_id"),
+	}
+}
+
+// passwordErrorResponse adds the individual rule violations to the error body
+// when a password was rejected by the password policy.
+func passwordErrorResponse(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+
+	var policyErr *auth.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		body["details"] = policyErr.Violations
+	}
+
+	return body
+}
+
+// registrationRefused reports whether err means the registration mode does not
+// allow the account to be created.
+func registrationRefused(err error) bool {
+	return errors.Is(err, usecase.ErrInviteRequired) || errors.Is(err, usecase.ErrEmailDomainNotAllowed) ||
+		errors.Is(err, usecase.ErrDisposableEmail)
+}
+
+// abortIfThrottled answers 429 with Retry-After when err is a login or sign-in
+// link throttle refusal.
+func abortIfThrottled(c *gin.Context, err error) bool {
+	var throttled *usecase.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after": seconds})
+	return true
+}
+
 func (h *AuthHandler) applySameSite(c *gin.Context) {
 	switch strings.ToLower(h.config.Cookie.SameSite) {
 	case "strict":
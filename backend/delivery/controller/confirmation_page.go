@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// confirmation is a page that asks the user to press a button before an
+// emailed link takes effect. Mail scanners and link previews open links with
+// GET, so the action itself only runs on the POST the button sends.
+type confirmation struct {
+	Title   string
+	Message string
+	Button  string
+	// Fields are posted back as hidden form values
+	Fields map[string]string
+}
+
+var confirmationTemplate = template.Must(template.New("confirmation").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - DevFolio</title>
+<style>
+body { font-family: system-ui, sans-serif; display: flex; justify-content: center; padding: 4rem 1rem; }
+main { max-width: 28rem; text-align: center; }
+button { font-size: 1rem; padding: 0.6rem 1.5rem; cursor: pointer; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+<form method="post">
+{{range $name, $value := .Fields}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<button type="submit">{{.Button}}</button>
+</form>
+</main>
+</body>
+</html>
+`))
+
+// renderConfirmation answers with the page. The form posts back to the URL it
+// was served from.
+func renderConfirmation(c *gin.Context, page confirmation) {
+	// The URL carries a token, so keep it out of caches and other sites'
+	// Referer headers, and keep the button from being clicked through a frame.
+	// "no-referrer" would make the form post with Origin: null, which CORS refuses.
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "same-origin")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	if err := confirmationTemplate.Execute(c.Writer, page); err != nil {
+		log.Printf("Failed to render confirmation page: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportHandler struct {
+	exportUsecase usecase.DataExportUsecase
+}
+
+func NewDataExportHandler(exportUsecase usecase.DataExportUsecase) *DataExportHandler {
+	return &DataExportHandler{
+		exportUsecase: exportUsecase,
+	}
+}
+
+// RequestExport starts a data export. The archive is built in the background,
+// so clients poll GetExport until it is ready.
+func (h *DataExportHandler) RequestExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	export, err := h.exportUsecase.RequestExport(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusAccepted
+	if export.Status == entities.DataExportReady {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{"data": export})
+}
+
+func (h *DataExportHandler) GetExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	export, err := h.exportUsecase.GetExport(c.Request.Context(), userID.(string), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": export})
+}
+
+// DownloadExport serves the archive. It is authorized by the signed token in
+// the link rather than a bearer token, so browsers can follow it directly.
+func (h *DataExportHandler) DownloadExport(c *gin.Context) {
+	path, err := h.exportUsecase.OpenExport(c.Request.Context(), c.Param("id"), c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(path, fmt.Sprintf("devfolio-data-%s.zip", time.Now().UTC().Format("2006-01-02")))
+}
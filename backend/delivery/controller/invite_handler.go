@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"net/http"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InviteHandler struct {
+	inviteUsecase usecase.InviteUsecase
+}
+
+func NewInviteHandler(inviteUsecase usecase.InviteUsecase) *InviteHandler {
+	return &InviteHandler{
+		inviteUsecase: inviteUsecase,
+	}
+}
+
+// CreateInvite returns the new invite code. It is never shown again.
+func (h *InviteHandler) CreateInvite(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req entities.CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invite, err := h.inviteUsecase.CreateInvite(c.Request.Context(), actorID.(string), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": invite})
+}
+
+func (h *InviteHandler) ListInvites(c *gin.Context) {
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	invites, err := h.inviteUsecase.ListInvites(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": invites})
+}
+
+func (h *InviteHandler) RevokeInvite(c *gin.Context) {
+	if err := h.inviteUsecase.RevokeInvite(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invite revoked"})
+}
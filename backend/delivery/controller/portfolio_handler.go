@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -92,9 +93,13 @@ func (h *PortfolioHandler) UpdatePortfolio(c *gin.Context) {
 		return
 	}
 
-	portfolio, err := h.portfolioUsecase.UpdatePortfolio(c.Request.Context(), id, &req, userID.(string))
+	portfolio, err := h.portfolioUsecase.UpdatePortfolio(c.Request.Context(), id, &req, userID.(string), clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, usecase.ErrEmailNotVerified) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
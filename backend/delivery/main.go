@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"strings"
 
 	ctrl "devfolio-backend/delivery/controller"
 	"devfolio-backend/delivery/router"
@@ -10,6 +12,8 @@ import (
 	"devfolio-backend/infrastructure/auth"
 	"devfolio-backend/infrastructure/config"
 	"devfolio-backend/infrastructure/database"
+	"devfolio-backend/infrastructure/mail"
+	"devfolio-backend/infrastructure/storage"
 	"devfolio-backend/repositories"
 	"devfolio-backend/usecase"
 )
@@ -24,6 +28,13 @@ func main() {
 	var (
 		portfolioRepo domainrepo.PortfolioRepository
 		userRepo      domainrepo.UserRepository
+		sessionRepo   domainrepo.SessionRepository
+		tokenRepo     domainrepo.OneTimeTokenRepository
+		attemptRepo   domainrepo.LoginAttemptRepository
+		patRepo       domainrepo.PersonalAccessTokenRepository
+		exportRepo    domainrepo.DataExportRepository
+		auditRepo     domainrepo.AuditEventRepository
+		inviteRepo    domainrepo.InviteRepository
 	)
 
 	// Initialize MongoDB, but fall back to in-memory repositories when it is unavailable.
@@ -35,6 +46,13 @@ func main() {
 		store := repositories.NewMemoryStore()
 		portfolioRepo = repositories.NewMemoryPortfolioRepository(store)
 		userRepo = repositories.NewMemoryUserRepository(store)
+		sessionRepo = repositories.NewMemorySessionRepository(store)
+		tokenRepo = repositories.NewMemoryOneTimeTokenRepository(store)
+		attemptRepo = repositories.NewMemoryLoginAttemptRepository(store)
+		patRepo = repositories.NewMemoryPersonalAccessTokenRepository(store)
+		exportRepo = repositories.NewMemoryDataExportRepository(store)
+		auditRepo = repositories.NewMemoryAuditEventRepository(store)
+		inviteRepo = repositories.NewMemoryInviteRepository(store)
 	} else {
 		defer func() {
 			if err := db.Close(); err != nil {
@@ -44,6 +62,21 @@ func main() {
 
 		portfolioRepo = repositories.NewPortfolioRepository(db)
 		userRepo = repositories.NewUserRepository(db)
+		sessionRepo = repositories.NewSessionRepository(db)
+		tokenRepo = repositories.NewOneTimeTokenRepository(db)
+		patRepo = repositories.NewPersonalAccessTokenRepository(db)
+		exportRepo = repositories.NewDataExportRepository(db)
+		auditRepo = repositories.NewAuditEventRepository(db)
+		inviteRepo = repositories.NewInviteRepository(db)
+
+		switch cfg.LoginThrottle.Storage {
+		case "mongo":
+			attemptRepo = repositories.NewLoginAttemptRepository(db)
+		case "memory":
+			attemptRepo = repositories.NewMemoryLoginAttemptRepository(repositories.NewMemoryStore())
+		default:
+			log.Fatalf("Unknown login throttle storage %q", cfg.LoginThrottle.Storage)
+		}
 	}
 
 	// Initialize AI client
@@ -54,18 +87,66 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize JWT manager: %v", err)
 	}
-	passwordManager := auth.NewPasswordManager()
+	if jwtManager.UsesSharedSecret() {
+		log.Printf("JWT_SIGNING_KEY_FILE is not set, signing tokens with the shared HS256 secret. This is only allowed in debug mode.")
+	}
+	passwordManager, err := auth.NewPasswordManager(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize password manager: %v", err)
+	}
+
+	oauthRegistry, err := auth.NewOAuthRegistry(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize login providers: %v", err)
+	}
+
+	// Initialize mailer
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	exportFiles := storage.NewFileStore(cfg.Export.Dir)
 
 	// Initialize use cases
-	portfolioUsecase := usecase.NewPortfolioUsecase(portfolioRepo, aiClient)
-	authUsecase := usecase.NewAuthUsecase(userRepo, jwtManager, passwordManager)
+	portfolioUsecase := usecase.NewPortfolioUsecase(portfolioRepo, userRepo, auditRepo, aiClient, cfg.Auth.RequireVerifiedEmailToPublish)
+	authUsecase, err := usecase.NewAuthUsecase(userRepo, sessionRepo, tokenRepo, attemptRepo, auditRepo, inviteRepo, jwtManager, passwordManager, mailer, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize auth use case: %v", err)
+	}
+	accountUsecase, err := usecase.NewAccountUsecase(authUsecase, userRepo, portfolioRepo, sessionRepo, patRepo, tokenRepo, exportRepo, attemptRepo, auditRepo, exportFiles, mailer, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize account use case: %v", err)
+	}
+	go accountUsecase.RunPurger(context.Background())
+	adminUsecase, err := usecase.NewAdminUsecase(userRepo, portfolioRepo, sessionRepo, patRepo, tokenRepo, exportRepo, attemptRepo, auditRepo, exportFiles, jwtManager, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize admin use case: %v", err)
+	}
+	if cfg.Auth.AdminEmails != "" {
+		adminUsecase.PromoteAdmins(context.Background(), strings.Split(cfg.Auth.AdminEmails, ","))
+	}
+	exportUsecase, err := usecase.NewDataExportUsecase(exportRepo, userRepo, portfolioRepo, sessionRepo, patRepo, auditRepo, exportFiles, jwtManager, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize data export use case: %v", err)
+	}
+	go exportUsecase.RunCleanup(context.Background())
 
 	// Initialize handlers
 	portfolioHandler := ctrl.NewPortfolioHandler(portfolioUsecase)
-	authHandler := ctrl.NewAuthHandler(authUsecase, cfg)
+	authHandler := ctrl.NewAuthHandler(authUsecase, accountUsecase, oauthRegistry, cfg)
+	adminHandler := ctrl.NewAdminHandler(adminUsecase)
+	tokenUsecase := usecase.NewPersonalAccessTokenUsecase(patRepo, userRepo)
+	tokenHandler := ctrl.NewPersonalAccessTokenHandler(tokenUsecase)
+	exportHandler := ctrl.NewDataExportHandler(exportUsecase)
+	auditHandler := ctrl.NewAuditHandler(usecase.NewAuditUsecase(auditRepo))
+	inviteHandler := ctrl.NewInviteHandler(usecase.NewInviteUsecase(inviteRepo))
 
 	// Setup routes
-	ginRouter := router.SetupRoutes(portfolioHandler, authHandler, jwtManager, cfg)
+	ginRouter, err := router.SetupRoutes(portfolioHandler, authHandler, adminHandler, tokenHandler, exportHandler, auditHandler, inviteHandler, jwtManager, sessionRepo, tokenUsecase, cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up routes: %v", err)
+	}
 
 	// Start server
 	log.Printf("Starting server on port %s", cfg.Server.Port)
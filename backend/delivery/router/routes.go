@@ -1,7 +1,12 @@
 package router
 
 import (
+	"fmt"
+	"strings"
+
 	ctrl "devfolio-backend/delivery/controller"
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
 	"devfolio-backend/infrastructure/auth"
 	"devfolio-backend/infrastructure/config"
 	"devfolio-backend/infrastructure/middleware"
@@ -13,19 +18,39 @@ import (
 func SetupRoutes(
 	portfolioHandler *ctrl.PortfolioHandler,
 	authHandler *ctrl.AuthHandler,
+	adminHandler *ctrl.AdminHandler,
+	tokenHandler *ctrl.PersonalAccessTokenHandler,
+	exportHandler *ctrl.DataExportHandler,
+	auditHandler *ctrl.AuditHandler,
+	inviteHandler *ctrl.InviteHandler,
 	jwtManager *auth.JWTManager,
+	sessionRepo repositories.SessionRepository,
+	tokens middleware.TokenAuthenticator,
 	cfg *config.Config,
-) *gin.Engine {
+) (*gin.Engine, error) {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.GinMode)
 	
 	router := gin.Default()
 
+	// Client IPs drive login throttling and the audit log, so X-Forwarded-For
+	// is only believed when it comes from a configured proxy
+	var proxies []string
+	for _, proxy := range strings.Split(cfg.Server.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	// CORS middleware
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{cfg.CORS.FrontendURL}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-User-ID"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-User-ID", middleware.CSRFHeaderName}
+	corsConfig.ExposeHeaders = []string{middleware.CSRFHeaderName}
 	corsConfig.AllowCredentials = true
 	
 	router.Use(cors.New(corsConfig))
@@ -35,6 +60,12 @@ func SetupRoutes(
 		c.JSON(200, gin.H{"status": "ok", "message": "DevFolio API is running"})
 	})
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, jwtManager.JWKS())
+	})
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
@@ -43,41 +74,110 @@ func SetupRoutes(
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
-			auth.POST("/refresh", authHandler.RefreshToken)
-			auth.GET("/google/login", authHandler.GoogleLogin)
-			auth.GET("/google/callback", authHandler.GoogleCallback)
+			auth.POST("/login/mfa", authHandler.LoginMFA)
+			auth.POST("/passkeys/login/begin", authHandler.BeginPasskeyLogin)
+			auth.POST("/passkeys/login/finish", authHandler.FinishPasskeyLogin)
+			auth.GET("/csrf", authHandler.CSRFToken)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/confirm-email-change", authHandler.ConfirmEmailChange)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/magic-link", authHandler.RequestMagicLink)
+			auth.GET("/magic-link/verify", authHandler.ConfirmMagicLink)
+			auth.POST("/magic-link/verify", authHandler.MagicLinkCallback)
+			auth.GET("/secure-account", authHandler.ConfirmSecureAccount)
+			auth.POST("/secure-account", authHandler.SecureAccountCallback)
+			auth.GET("/providers", authHandler.ListOAuthProviders)
+			auth.GET("/:provider/login", authHandler.OAuthLogin)
+			auth.GET("/:provider/callback", authHandler.OAuthCallback)
+			auth.GET("/exports/:id/download", exportHandler.DownloadExport)
+		}
+
+		// Routes authenticated by the refresh token cookie
+		cookieAuth := v1.Group("/auth")
+		cookieAuth.Use(middleware.CSRFMiddleware(cfg.CORS.FrontendURL))
+		{
+			cookieAuth.POST("/refresh", authHandler.RefreshToken)
 		}
 
 		// Protected auth routes
 		authProtected := v1.Group("/auth")
-		authProtected.Use(middleware.AuthMiddleware(jwtManager))
+		authProtected.Use(middleware.AuthMiddleware(jwtManager, sessionRepo, nil))
 		{
 			authProtected.POST("/logout", authHandler.Logout)
 			authProtected.GET("/profile", authHandler.GetProfile)
-			authProtected.PUT("/profile", authHandler.UpdateProfile)
-			authProtected.PUT("/change-password", authHandler.ChangePassword)
+			authProtected.DELETE("/account", middleware.DenyImpersonation(), authHandler.DeleteAccount)
+			authProtected.PUT("/profile", middleware.DenyImpersonation(), authHandler.UpdateProfile)
+			authProtected.PUT("/change-password", middleware.DenyImpersonation(), authHandler.ChangePassword)
+			authProtected.POST("/change-email", middleware.DenyImpersonation(), authHandler.ChangeEmail)
+			authProtected.POST("/resend-verification", authHandler.ResendVerification)
+			authProtected.POST("/2fa/enroll", middleware.DenyImpersonation(), authHandler.EnrollTwoFactor)
+			authProtected.POST("/2fa/confirm", middleware.DenyImpersonation(), authHandler.ConfirmTwoFactor)
+			authProtected.POST("/2fa/disable", middleware.DenyImpersonation(), authHandler.DisableTwoFactor)
+			authProtected.POST("/passkeys/register/begin", middleware.DenyImpersonation(), authHandler.BeginPasskeyRegistration)
+			authProtected.POST("/passkeys/register/finish", middleware.DenyImpersonation(), authHandler.FinishPasskeyRegistration)
+			authProtected.GET("/passkeys", authHandler.ListPasskeys)
+			authProtected.DELETE("/passkeys/:id", middleware.DenyImpersonation(), authHandler.DeletePasskey)
+			authProtected.GET("/identities", authHandler.ListIdentities)
+			authProtected.POST("/identities/:provider", middleware.DenyImpersonation(), authHandler.LinkIdentity)
+			authProtected.DELETE("/identities/:provider", middleware.DenyImpersonation(), authHandler.UnlinkIdentity)
+			authProtected.GET("/sessions", authHandler.ListSessions)
+			authProtected.GET("/activity", auditHandler.ListActivity)
+			authProtected.DELETE("/sessions", middleware.DenyImpersonation(), authHandler.RevokeAllSessions)
+			authProtected.DELETE("/sessions/:id", middleware.DenyImpersonation(), authHandler.RevokeSession)
+			authProtected.POST("/tokens", middleware.DenyImpersonation(), tokenHandler.CreateToken)
+			authProtected.GET("/tokens", tokenHandler.ListTokens)
+			authProtected.DELETE("/tokens/:id", middleware.DenyImpersonation(), tokenHandler.RevokeToken)
+			authProtected.POST("/exports", middleware.DenyImpersonation(), exportHandler.RequestExport)
+			authProtected.GET("/exports/:id", middleware.DenyImpersonation(), exportHandler.GetExport)
 		}
 
 		// Portfolio routes
 		portfolios := v1.Group("/portfolios")
-		portfolios.Use(middleware.OptionalAuthMiddleware(jwtManager)) // Some endpoints allow optional auth
+		portfolios.Use(middleware.OptionalAuthMiddleware(jwtManager, sessionRepo)) // Some endpoints allow optional auth
 		{
 			portfolios.GET("/public", portfolioHandler.GetPublicPortfolios)
 			portfolios.GET("/search", portfolioHandler.SearchPortfolios)
 			portfolios.GET("/:id", portfolioHandler.GetPortfolio)
 		}
 
-		// Protected portfolio routes
+		// Protected portfolio routes, also open to personal access tokens with the right scope
 		portfoliosProtected := v1.Group("/portfolios")
-		portfoliosProtected.Use(middleware.AuthMiddleware(jwtManager))
+		portfoliosProtected.Use(middleware.AuthMiddleware(jwtManager, sessionRepo, tokens))
+		{
+			portfoliosProtected.POST("", middleware.RequireScope(entities.ScopePortfolioWrite), portfolioHandler.CreatePortfolio)
+			portfoliosProtected.GET("/user", middleware.RequireScope(entities.ScopePortfolioRead), portfolioHandler.GetUserPortfolios)
+			portfoliosProtected.PUT("/:id", middleware.RequireScope(entities.ScopePortfolioWrite), portfolioHandler.UpdatePortfolio)
+			portfoliosProtected.DELETE("/:id", middleware.RequireScope(entities.ScopePortfolioWrite), portfolioHandler.DeletePortfolio)
+			portfoliosProtected.POST("/enhance", middleware.RequireScope(entities.ScopePortfolioWrite), portfolioHandler.EnhanceWithAI)
+		}
+
+		// Support routes for moderators and admins
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(jwtManager, sessionRepo, nil), middleware.RequireRole(entities.RoleModerator))
+		{
+			admin.GET("/users", adminHandler.ListUsers)
+			admin.GET("/users/:id", adminHandler.GetUser)
+			admin.GET("/portfolios", adminHandler.ListPortfolios)
+			admin.PUT("/portfolios/:id", adminHandler.UpdatePortfolio)
+			admin.DELETE("/portfolios/:id", adminHandler.DeletePortfolio)
+		}
+
+		// Account management is limited to admins
+		adminOnly := v1.Group("/admin")
+		adminOnly.Use(middleware.AuthMiddleware(jwtManager, sessionRepo, nil), middleware.RequireRole(entities.RoleAdmin))
 		{
-			portfoliosProtected.POST("", portfolioHandler.CreatePortfolio)
-			portfoliosProtected.GET("/user", portfolioHandler.GetUserPortfolios)
-			portfoliosProtected.PUT("/:id", portfolioHandler.UpdatePortfolio)
-			portfoliosProtected.DELETE("/:id", portfolioHandler.DeletePortfolio)
-			portfoliosProtected.POST("/enhance", portfolioHandler.EnhanceWithAI)
+			adminOnly.PUT("/users/:id/role", adminHandler.SetUserRole)
+			adminOnly.POST("/users/:id/deactivate", adminHandler.DeactivateUser)
+			adminOnly.POST("/users/:id/reactivate", adminHandler.ReactivateUser)
+			adminOnly.DELETE("/users/:id", adminHandler.DeleteUser)
+			adminOnly.POST("/users/:id/impersonate", adminHandler.ImpersonateUser)
+			adminOnly.GET("/audit-events", auditHandler.ListEvents)
+			adminOnly.POST("/invites", inviteHandler.CreateInvite)
+			adminOnly.GET("/invites", inviteHandler.ListInvites)
+			adminOnly.DELETE("/invites/:id", inviteHandler.RevokeInvite)
 		}
 	}
 
-	return router
+	return router, nil
 }
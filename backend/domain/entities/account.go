@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+// ReauthenticateRequest confirms the user's identity before a sensitive
+// change. Password is required for accounts that have one, and Code when
+// two-factor authentication is enabled.
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// ChangeEmailRequest asks to move the account to a new address. Nothing
+// changes until the link sent to that address is opened.
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	ReauthenticateRequest
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
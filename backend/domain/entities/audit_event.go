@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit event types.
+const (
+	AuditRegister                   = "register"
+	AuditLoginSucceeded             = "login_succeeded"
+	AuditLoginFailed                = "login_failed"
+	AuditProviderLogin              = "provider_login"
+	AuditTokenRefreshed             = "token_refreshed"
+	AuditLogout                     = "logout"
+	AuditPasswordChanged            = "password_changed"
+	AuditProfileUpdated             = "profile_updated"
+	AuditEmailChanged               = "email_changed"
+	AuditPortfolioVisibilityChanged = "portfolio_visibility_changed"
+	AuditImpersonationStarted       = "impersonation_started"
+	AuditNewDeviceLogin             = "new_device_login"
+	AuditAccountSecured             = "account_secured"
+)
+
+// AuditEvent records a security relevant action on an account, and where it
+// came from.
+type AuditEvent struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type   string             `json:"type" bson:"type"`
+	UserID string             `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	// ActorID is set when someone else, such as an admin, acted on the user's behalf
+	ActorID string `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	// Email is the address used, which matters for failed logins of unknown accounts
+	Email     string            `json:"email,omitempty" bson:"email,omitempty"`
+	IPAddress string            `json:"ip_address" bson:"ip_address"`
+	UserAgent string            `json:"user_agent" bson:"user_agent"`
+	Details   map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at" bson:"created_at"`
+}
+
+// AuditEventFilter narrows an audit log query. Empty fields match everything.
+type AuditEventFilter struct {
+	UserID    string
+	Type      string
+	Email     string
+	IPAddress string
+	Since     *time.Time
+	Until     *time.Time
+}
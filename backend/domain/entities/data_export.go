@@ -0,0 +1,59 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Data export states. An export starts pending and ends ready or failed.
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is a "download my data" archive. The archive is built in the
+// background and deleted together with the record once it expires.
+type DataExport struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID      string             `json:"-" bson:"user_id"`
+	Status      string             `json:"status" bson:"status"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	FileName    string             `json:"-" bson:"file_name,omitempty"`
+	Size        int64              `json:"size,omitempty" bson:"size,omitempty"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ExpiresAt   time.Time          `json:"expires_at" bson:"expires_at"`
+	// DownloadURL is only set on ready exports and expires well before the export does
+	DownloadURL string `json:"download_url,omitempty" bson:"-"`
+}
+
+// IsExpired reports whether the export should no longer be served.
+func (e *DataExport) IsExpired() bool {
+	return !time.Now().Before(e.ExpiresAt)
+}
+
+// DataExportManifest describes the archive contents in manifest.json.
+type DataExportManifest struct {
+	FormatVersion int                      `json:"format_version"`
+	ExportID      string                   `json:"export_id"`
+	UserID        string                   `json:"user_id"`
+	GeneratedAt   time.Time                `json:"generated_at"`
+	Files         []DataExportManifestFile `json:"files"`
+	// Excluded lists data the archive deliberately does not contain
+	Excluded []DataExportManifestExclusion `json:"excluded"`
+}
+
+type DataExportManifestFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Records     int    `json:"records"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+type DataExportManifestExclusion struct {
+	Data   string `json:"data"`
+	Reason string `json:"reason"`
+}
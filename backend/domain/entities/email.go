@@ -0,0 +1,61 @@
+package entities
+
+import "strings"
+
+// emailProvider describes how a mail provider treats local parts: whether it
+// ignores dots and whether everything after a "+" is a tag on the same inbox.
+type emailProvider struct {
+	canonicalDomain string
+	ignoreDots      bool
+	plusTags        bool
+}
+
+var emailProviders = map[string]emailProvider{
+	"gmail.com":      {canonicalDomain: "gmail.com", ignoreDots: true, plusTags: true},
+	"googlemail.com": {canonicalDomain: "gmail.com", ignoreDots: true, plusTags: true},
+	"outlook.com":    {canonicalDomain: "outlook.com", plusTags: true},
+	"hotmail.com":    {canonicalDomain: "hotmail.com", plusTags: true},
+	"live.com":       {canonicalDomain: "live.com", plusTags: true},
+	"icloud.com":     {canonicalDomain: "icloud.com", plusTags: true},
+	"me.com":         {canonicalDomain: "icloud.com", plusTags: true},
+	"mac.com":        {canonicalDomain: "icloud.com", plusTags: true},
+	"protonmail.com": {canonicalDomain: "proton.me", plusTags: true},
+	"protonmail.ch":  {canonicalDomain: "proton.me", plusTags: true},
+	"proton.me":      {canonicalDomain: "proton.me", plusTags: true},
+	"pm.me":          {canonicalDomain: "proton.me", plusTags: true},
+	"fastmail.com":   {canonicalDomain: "fastmail.com", plusTags: true},
+}
+
+// CanonicalEmail returns the key two addresses share when they reach the same
+// inbox, such as a.b+x@gmail.com and ab@googlemail.com. Addresses at unknown
+// providers are only lower-cased, since their local parts may be significant.
+func CanonicalEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+	provider, ok := emailProviders[domain]
+	if !ok {
+		return local + "@" + domain
+	}
+
+	if provider.plusTags {
+		if plus := strings.Index(local, "+"); plus > 0 {
+			local = local[:plus]
+		}
+	}
+	if provider.ignoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+
+	return local + "@" + provider.canonicalDomain
+}
+
+// EmailDomain returns the lower-cased part of the address after the last "@".
+func EmailDomain(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	return strings.TrimSuffix(email[strings.LastIndex(email, "@")+1:], ".")
+}
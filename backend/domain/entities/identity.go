@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+// LinkedIdentity is an external provider account the user can sign in with.
+type LinkedIdentity struct {
+	Provider   string     `json:"provider" bson:"provider"`
+	Subject    string     `json:"-" bson:"subject"` // the provider's stable user ID
+	Email      string     `json:"email" bson:"email"`
+	LinkedAt   time.Time  `json:"linked_at" bson:"linked_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+}
+
+// OAuthLinkResponse carries the provider page the browser must visit to finish linking.
+type OAuthLinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
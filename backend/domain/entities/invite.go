@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Registration modes.
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+	RegistrationDomain = "domain"
+)
+
+// Invite lets people sign up while registration is invite-only. Only the hash
+// of the code is stored.
+type Invite struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CodeHash string             `json:"-" bson:"code_hash"`
+	// Email restricts the invite to one address when set
+	Email     string     `json:"email,omitempty" bson:"email,omitempty"`
+	Note      string     `json:"note,omitempty" bson:"note,omitempty"`
+	MaxUses   int        `json:"max_uses" bson:"max_uses"`
+	Uses      int        `json:"uses" bson:"uses"`
+	CreatedBy string     `json:"created_by" bson:"created_by"`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// IsUsable reports whether the invite is neither revoked, expired nor used up.
+func (i *Invite) IsUsable() bool {
+	return i.RevokedAt == nil && time.Now().Before(i.ExpiresAt) && i.Uses < i.MaxUses
+}
+
+type CreateInviteRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+	Note  string `json:"note" binding:"max=200"`
+	// MaxUses defaults to 1, a single-use invite
+	MaxUses int `json:"max_uses" binding:"omitempty,min=1,max=10000"`
+	// ExpiresInDays defaults to 7
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// CreateInviteResponse is the only response that contains the invite code itself.
+type CreateInviteResponse struct {
+	*Invite
+	Code string `json:"code"`
+}
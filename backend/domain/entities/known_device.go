@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KnownDevice is a device and network the user has signed in from before. A
+// sign-in that matches none of them is reported to the user.
+type KnownDevice struct {
+	ID primitive.ObjectID `json:"id" bson:"_id"`
+	// Fingerprint is a hash of the user agent with version numbers removed, so
+	// browser updates do not count as a new device
+	Fingerprint string `json:"-" bson:"fingerprint"`
+	// Network is the /24 (IPv4) or /48 (IPv6) prefix of the address
+	Network     string    `json:"network" bson:"network"`
+	UserAgent   string    `json:"user_agent" bson:"user_agent"`
+	IPAddress   string    `json:"ip_address" bson:"ip_address"`
+	FirstSeenAt time.Time `json:"first_seen_at" bson:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" bson:"last_seen_at"`
+}
@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// LoginAttempt counts recent failed sign-ins for one key, such as an email
+// address or a client IP. Keys with a magic_link prefix count sign-in link
+// requests instead.
+type LoginAttempt struct {
+	Key           string     `json:"key" bson:"_id"`
+	Failures      int        `json:"failures" bson:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" bson:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	ExpiresAt     time.Time  `json:"expires_at" bson:"expires_at"`
+}
@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TokenPurposePasswordReset       = "password_reset"
+	TokenPurposePasskeyRegistration = "passkey_registration"
+	TokenPurposePasskeyLogin        = "passkey_login"
+	TokenPurposeIdentityLink        = "identity_link"
+	TokenPurposeEmailChange         = "email_change"
+	TokenPurposeMagicLink           = "magic_link"
+	TokenPurposeSecureAccount       = "secure_account"
+)
+
+// OneTimeToken is an expiring, single-use token that is mailed to a user. Only the
+// hash of the token is stored.
+type OneTimeToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Email     string             `json:"email" bson:"email"` // for email changes, the new address
+	Purpose   string             `json:"purpose" bson:"purpose"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	DeviceID  string             `json:"-" bson:"device_id,omitempty"` // for secure_account, the device being reported
+	UsedAt    *time.Time         `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// IsUsable reports whether the token has neither been used nor expired.
+func (t *OneTimeToken) IsUsable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
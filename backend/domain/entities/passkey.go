@@ -0,0 +1,95 @@
+package entities
+
+import "time"
+
+// PasskeyCredential is a WebAuthn credential registered to a user.
+type PasskeyCredential struct {
+	ID         string     `json:"id" bson:"id"` // base64url credential ID
+	Name       string     `json:"name" bson:"name"`
+	PublicKey  []byte     `json:"-" bson:"public_key"` // COSE encoded
+	SignCount  uint32     `json:"-" bson:"sign_count"`
+	Transports []string   `json:"transports" bson:"transports"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+}
+
+// The option and response types below follow the WebAuthn JSON serialization,
+// so they use the camelCase names the browser APIs expect.
+
+type PasskeyRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type PasskeyUserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type PasskeyCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type PasskeyCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+type PasskeyCreationOptions struct {
+	Challenge              string                        `json:"challenge"`
+	RP                     PasskeyRelyingParty           `json:"rp"`
+	User                   PasskeyUserEntity             `json:"user"`
+	PubKeyCredParams       []PasskeyCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                           `json:"timeout"`
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                        `json:"attestation"`
+}
+
+type PasskeyRequestOptions struct {
+	Challenge        string `json:"challenge"`
+	Timeout          int    `json:"timeout"`
+	RPID             string `json:"rpId"`
+	UserVerification string `json:"userVerification"`
+}
+
+type PasskeyAttestationResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+	AttestationObject string   `json:"attestationObject" binding:"required"`
+	Transports        []string `json:"transports"`
+}
+
+type PasskeyAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData string `json:"authenticatorData" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+	UserHandle        string `json:"userHandle" binding:"required"`
+}
+
+type PasskeyRegistrationCredential struct {
+	ID       string                     `json:"id" binding:"required"`
+	RawID    string                     `json:"rawId"`
+	Type     string                     `json:"type" binding:"required"`
+	Response PasskeyAttestationResponse `json:"response" binding:"required"`
+}
+
+type PasskeyRegistrationRequest struct {
+	Name       string                        `json:"name"`
+	Credential PasskeyRegistrationCredential `json:"credential" binding:"required"`
+}
+
+type PasskeyLoginRequest struct {
+	ID       string                   `json:"id" binding:"required"`
+	RawID    string                   `json:"rawId"`
+	Type     string                   `json:"type" binding:"required"`
+	Response PasskeyAssertionResponse `json:"response" binding:"required"`
+}
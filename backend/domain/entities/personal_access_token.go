@@ -0,0 +1,64 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, which tells
+// them apart from JWTs and makes leaked tokens easy to scan for.
+const PersonalAccessTokenPrefix = "dfp_"
+
+// Scopes a personal access token can be granted.
+const (
+	ScopePortfolioRead  = "portfolio:read"
+	ScopePortfolioWrite = "portfolio:write"
+)
+
+// PersonalAccessTokenScopes lists every scope in the order they are shown.
+var PersonalAccessTokenScopes = []string{ScopePortfolioRead, ScopePortfolioWrite}
+
+// PersonalAccessToken is a long-lived credential for scripts. It only works on
+// routes that accept one of its scopes, and only its hash is stored.
+type PersonalAccessToken struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID string             `json:"-" bson:"user_id"`
+	Name   string             `json:"name" bson:"name"`
+	Scopes []string           `json:"scopes" bson:"scopes"`
+	// Prefix is the start of the token, so users can tell their tokens apart
+	Prefix     string     `json:"prefix" bson:"prefix"`
+	TokenHash  string     `json:"-" bson:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" bson:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// IsExpired reports whether the token can no longer be used.
+func (t *PersonalAccessToken) IsExpired() bool {
+	return !time.Now().Before(t.ExpiresAt)
+}
+
+// HasScope reports whether the token grants scope. Write access to portfolios
+// includes read access.
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope || (granted == ScopePortfolioWrite && scope == ScopePortfolioRead) {
+			return true
+		}
+	}
+	return false
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays defaults to 30
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// CreatePersonalAccessTokenResponse is the only response that contains the token itself.
+type CreatePersonalAccessTokenResponse struct {
+	*PersonalAccessToken
+	Token string `json:"token"`
+}
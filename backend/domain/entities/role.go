@@ -0,0 +1,66 @@
+package entities
+
+import "time"
+
+// Roles in increasing order of privilege. Each role can do everything the
+// roles before it can.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// IsValidRole reports whether role is one of the known roles.
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants everything required grants. An empty
+// role is treated as RoleUser.
+func RoleAtLeast(role, required string) bool {
+	if role == "" {
+		role = RoleUser
+	}
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// UserFilter narrows the users listed through the admin API.
+type UserFilter struct {
+	// Query matches email, first name or last name, case-insensitively
+	Query string
+	Role  string
+	// Status is "active", "inactive" or empty for both
+	Status string
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ImpersonateRequest starts an impersonation. The reason is kept in the audit log.
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ImpersonationResponse holds the access token an admin uses to act as the user.
+type ImpersonationResponse struct {
+	AccessToken string        `json:"access_token"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	User        *UserResponse `json:"user"`
+}
+
+// AdminUserResponse is the user as seen by moderators and admins.
+type AdminUserResponse struct {
+	*UserResponse
+	AuthProvider        string     `json:"auth_provider"`
+	IsActive            bool       `json:"is_active"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
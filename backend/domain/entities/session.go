@@ -0,0 +1,64 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one refresh token family. It is created on sign-in and the
+// stored token hash is rotated every time the refresh token is used.
+type Session struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID           string             `json:"user_id" bson:"user_id"`
+	RefreshTokenHash string             `json:"-" bson:"refresh_token_hash"`
+	IPAddress        string             `json:"ip_address" bson:"ip_address"`
+	UserAgent        string             `json:"user_agent" bson:"user_agent"`
+	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
+	LastUsedAt       time.Time          `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt        time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt        *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	// ImpersonatorID is the admin the session was started for, if any
+	ImpersonatorID string `json:"impersonator_id,omitempty" bson:"impersonator_id,omitempty"`
+}
+
+// IsActive reports whether the session can still be used to refresh tokens.
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+type SessionResponse struct {
+	ID         primitive.ObjectID `json:"id"`
+	IPAddress  string             `json:"ip_address"`
+	UserAgent  string             `json:"user_agent"`
+	CreatedAt  time.Time          `json:"created_at"`
+	LastUsedAt time.Time          `json:"last_used_at"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	Current    bool               `json:"current"`
+	// Impersonated marks sessions an admin opened to act as the user
+	Impersonated bool `json:"impersonated,omitempty"`
+}
+
+// ToResponse converts the session for API responses, flagging it when it is the
+// session the request was made with.
+func (s *Session) ToResponse(currentSessionID string) *SessionResponse {
+	return &SessionResponse{
+		ID:           s.ID,
+		IPAddress:    s.IPAddress,
+		UserAgent:    s.UserAgent,
+		CreatedAt:    s.CreatedAt,
+		LastUsedAt:   s.LastUsedAt,
+		ExpiresAt:    s.ExpiresAt,
+		Current:      s.ID.Hex() == currentSessionID,
+		Impersonated: s.ImpersonatorID != "",
+	}
+}
+
+// ClientInfo describes the client a request came from.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+	// ImpersonatorID is the admin behind the request when it was made with an
+	// impersonation token
+	ImpersonatorID string
+}
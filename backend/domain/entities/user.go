@@ -1,37 +1,61 @@
 package entities
 
 import (
-	"time"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type User struct {
 	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Email        string            `json:"email" bson:"email"`
-	Password     string            `json:"-" bson:"password"` // Never include in JSON responses
-	AuthProvider string            `json:"auth_provider,omitempty" bson:"auth_provider,omitempty"`
-	GoogleID     string            `json:"google_id,omitempty" bson:"google_id,omitempty"`
-	FirstName    string            `json:"first_name" bson:"first_name"`
-	LastName     string            `json:"last_name" bson:"last_name"`
-	Avatar       string            `json:"avatar" bson:"avatar"`
-	Bio          string            `json:"bio" bson:"bio"`
-	Phone        string            `json:"phone" bson:"phone"`
-	Location     string            `json:"location" bson:"location"`
-	Website      string            `json:"website" bson:"website"`
-	LinkedIn     string            `json:"linkedin" bson:"linkedin"`
-	GitHub       string            `json:"github" bson:"github"`
-	IsVerified   bool              `json:"is_verified" bson:"is_verified"`
-	IsActive     bool              `json:"is_active" bson:"is_active"`
-	LastLoginAt  *time.Time        `json:"last_login_at,omitempty" bson:"last_login_at,omitempty"`
-	CreatedAt    time.Time         `json:"created_at" bson:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at" bson:"updated_at"`
+	Email        string             `json:"email" bson:"email"`
+	Password     string             `json:"-" bson:"password"` // Never include in JSON responses
+	AuthProvider string             `json:"auth_provider,omitempty" bson:"auth_provider,omitempty"`
+	GoogleID     string             `json:"google_id,omitempty" bson:"google_id,omitempty"`
+	Role         string             `json:"role" bson:"role"`
+	FirstName    string             `json:"first_name" bson:"first_name"`
+	LastName     string             `json:"last_name" bson:"last_name"`
+	Avatar       string             `json:"avatar" bson:"avatar"`
+	Bio          string             `json:"bio" bson:"bio"`
+	Phone        string             `json:"phone" bson:"phone"`
+	Location     string             `json:"location" bson:"location"`
+	Website      string             `json:"website" bson:"website"`
+	LinkedIn     string             `json:"linkedin" bson:"linkedin"`
+	GitHub       string             `json:"github" bson:"github"`
+	IsVerified   bool               `json:"is_verified" bson:"is_verified"`
+	// VerificationTokenID is the ID of the only verification token that may still be used.
+	VerificationTokenID string     `json:"-" bson:"verification_token_id"`
+	VerificationSentAt  *time.Time `json:"-" bson:"verification_sent_at"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled" bson:"two_factor_enabled"`
+	// TOTPSecret is set during enrollment and only trusted once TwoFactorEnabled is true.
+	TOTPSecret         string              `json:"-" bson:"totp_secret"`
+	TOTPLastUsedStep   int64               `json:"-" bson:"totp_last_used_step"`
+	RecoveryCodeHashes []string            `json:"-" bson:"recovery_code_hashes"`
+	Passkeys           []PasskeyCredential `json:"-" bson:"passkeys"`
+	Identities         []LinkedIdentity    `json:"-" bson:"identities"`
+	KnownDevices       []KnownDevice       `json:"-" bson:"known_devices"`
+	IsActive           bool                `json:"is_active" bson:"is_active"`
+	// EmailKey is CanonicalEmail(Email). No two active accounts share one, so
+	// accounts from before keys existed that share an address with an older
+	// one are left without a key.
+	EmailKey string `json:"-" bson:"email_key,omitempty"`
+	// PasswordResetRequired blocks password sign-in until the password is reset,
+	// after the user reported a sign-in that was not theirs.
+	PasswordResetRequired bool `json:"password_reset_required,omitempty" bson:"password_reset_required"`
+	// DeletionScheduledAt is set when the user deletes their account. Their data
+	// is purged at that time unless they sign in again before it.
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" bson:"deletion_scheduled_at"`
+	LastLoginAt         *time.Time `json:"last_login_at,omitempty" bson:"last_login_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" bson:"updated_at"`
 }
 
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
+	Password  string `json:"password" binding:"required"`
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
+	// InviteCode is required while registration is invite-only
+	InviteCode string `json:"invite_code"`
 }
 
 type LoginRequest struct {
@@ -44,12 +68,15 @@ type LoginResponse struct {
 	AccessToken string `json:"access_token"`
 }
 
-type GoogleProfile struct {
-	GoogleID  string
-	Email     string
-	FirstName string
-	LastName  string
-	Avatar    string
+// ExternalProfile is the user information returned by an OAuth or OpenID Connect provider.
+type ExternalProfile struct {
+	Provider      string
+	Subject       string // the provider's stable user ID
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Avatar        string
 }
 
 type RefreshTokenRequest struct {
@@ -72,46 +99,93 @@ type UpdateProfileRequest struct {
 	GitHub    *string `json:"github,omitempty"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	// InviteCode is passed on through the link, for addresses without an account
+	InviteCode string `json:"invite_code"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
-	NewPassword     string `json:"new_password" binding:"required,min=8"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 // UserResponse represents user data for API responses (without sensitive fields)
 type UserResponse struct {
-	ID          primitive.ObjectID `json:"id"`
-	Email       string            `json:"email"`
-	FirstName   string            `json:"first_name"`
-	LastName    string            `json:"last_name"`
-	Avatar      string            `json:"avatar"`
-	Bio         string            `json:"bio"`
-	Phone       string            `json:"phone"`
-	Location    string            `json:"location"`
-	Website     string            `json:"website"`
-	LinkedIn    string            `json:"linkedin"`
-	GitHub      string            `json:"github"`
-	IsVerified  bool              `json:"is_verified"`
-	LastLoginAt *time.Time        `json:"last_login_at,omitempty"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at"`
+	ID               primitive.ObjectID `json:"id"`
+	Email            string             `json:"email"`
+	FirstName        string             `json:"first_name"`
+	LastName         string             `json:"last_name"`
+	Avatar           string             `json:"avatar"`
+	Bio              string             `json:"bio"`
+	Phone            string             `json:"phone"`
+	Location         string             `json:"location"`
+	Website          string             `json:"website"`
+	LinkedIn         string             `json:"linkedin"`
+	GitHub           string             `json:"github"`
+	IsVerified       bool               `json:"is_verified"`
+	TwoFactorEnabled bool               `json:"two_factor_enabled"`
+	Role             string             `json:"role"`
+	LastLoginAt      *time.Time         `json:"last_login_at,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
 }
 
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:          u.ID,
-		Email:       u.Email,
-		FirstName:   u.FirstName,
-		LastName:    u.LastName,
-		Avatar:      u.Avatar,
-		Bio:         u.Bio,
-		Phone:       u.Phone,
-		Location:    u.Location,
-		Website:     u.Website,
-		LinkedIn:    u.LinkedIn,
-		GitHub:      u.GitHub,
-		IsVerified:  u.IsVerified,
-		LastLoginAt: u.LastLoginAt,
-		CreatedAt:   u.CreatedAt,
-		UpdatedAt:   u.UpdatedAt,
+		ID:               u.ID,
+		Email:            u.Email,
+		FirstName:        u.FirstName,
+		LastName:         u.LastName,
+		Avatar:           u.Avatar,
+		Bio:              u.Bio,
+		Phone:            u.Phone,
+		Location:         u.Location,
+		Website:          u.Website,
+		LinkedIn:         u.LinkedIn,
+		GitHub:           u.GitHub,
+		IsVerified:       u.IsVerified,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		Role:             u.EffectiveRole(),
+		LastLoginAt:      u.LastLoginAt,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
+	}
+}
+
+// PendingDeletion reports whether the user deleted their account and can still
+// restore it by signing in.
+func (u *User) PendingDeletion() bool {
+	return !u.IsActive && u.DeletionScheduledAt != nil && time.Now().Before(*u.DeletionScheduledAt)
+}
+
+// EffectiveRole is the user's role, treating accounts created before roles
+// existed as RoleUser.
+func (u *User) EffectiveRole() string {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+func (u *User) ToAdminResponse() *AdminUserResponse {
+	return &AdminUserResponse{
+		UserResponse:        u.ToResponse(),
+		AuthProvider:        u.AuthProvider,
+		IsActive:            u.IsActive,
+		DeletionScheduledAt: u.DeletionScheduledAt,
 	}
 }
@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"context"
+
+	"devfolio-backend/domain/entities"
+)
+
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *entities.AuditEvent) error
+	// List returns matching events, newest first.
+	List(ctx context.Context, filter entities.AuditEventFilter, limit, offset int) ([]*entities.AuditEvent, error)
+	GetByUserID(ctx context.Context, userID string) ([]*entities.AuditEvent, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *entities.DataExport) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.DataExport, error)
+	// GetLatestByUserID returns the user's most recently requested export.
+	GetLatestByUserID(ctx context.Context, userID string) (*entities.DataExport, error)
+	GetByUserID(ctx context.Context, userID string) ([]*entities.DataExport, error)
+	Update(ctx context.Context, export *entities.DataExport) error
+	GetExpired(ctx context.Context, now time.Time) ([]*entities.DataExport, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
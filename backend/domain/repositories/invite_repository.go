@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+
+	"devfolio-backend/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InviteRepository interface {
+	Create(ctx context.Context, invite *entities.Invite) error
+	GetByCodeHash(ctx context.Context, codeHash string) (*entities.Invite, error)
+	// List returns invites newest first.
+	List(ctx context.Context, limit, offset int) ([]*entities.Invite, error)
+	// Redeem uses up one use of the invite, failing if it is no longer usable.
+	Redeem(ctx context.Context, id primitive.ObjectID) error
+	// Release gives back a use taken by Redeem.
+	Release(ctx context.Context, id primitive.ObjectID) error
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"devfolio-backend/domain/entities"
+)
+
+type LoginAttemptRepository interface {
+	// Get returns the record for key, treating expired records as missing.
+	Get(ctx context.Context, key string) (*entities.LoginAttempt, error)
+	// RecordFailure counts one more failure for key and returns the updated
+	// record. An expired record starts counting again from one.
+	RecordFailure(ctx context.Context, key string, expiresAt time.Time) (*entities.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+
+	"devfolio-backend/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OneTimeTokenRepository interface {
+	Create(ctx context.Context, token *entities.OneTimeToken) error
+	GetByHash(ctx context.Context, tokenHash, purpose string) (*entities.OneTimeToken, error)
+	// Consume marks the token as used. It fails if the token was already used.
+	Consume(ctx context.Context, id primitive.ObjectID) error
+	DeleteByUserID(ctx context.Context, userID, purpose string) error
+	// DeleteAllByUserID removes the user's tokens of every purpose.
+	DeleteAllByUserID(ctx context.Context, userID string) error
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *entities.PersonalAccessToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*entities.PersonalAccessToken, error)
+	GetByUserID(ctx context.Context, userID string) ([]*entities.PersonalAccessToken, error)
+	UpdateLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+	// Delete only removes the token if it belongs to userID.
+	Delete(ctx context.Context, id primitive.ObjectID, userID string) error
+	DeleteByUserID(ctx context.Context, userID string) error
+}
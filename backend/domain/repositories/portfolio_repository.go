@@ -14,4 +14,8 @@ type PortfolioRepository interface {
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	GetPublicPortfolios(ctx context.Context, limit, offset int) ([]*entities.Portfolio, error)
 	Search(ctx context.Context, query string, limit, offset int) ([]*entities.Portfolio, error)
+	// ListAll includes private portfolios. An empty userID or query matches everything.
+	ListAll(ctx context.Context, userID, query string, limit, offset int) ([]*entities.Portfolio, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+	UnpublishByUserID(ctx context.Context, userID string) error
 }
\ No newline at end of file
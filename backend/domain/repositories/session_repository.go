@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *entities.Session) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.Session, error)
+	GetActiveByUserID(ctx context.Context, userID string) ([]*entities.Session, error)
+	// Rotate swaps the stored refresh token hash only if it still matches oldHash.
+	Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+	RevokeAllByUserID(ctx context.Context, userID string) error
+}
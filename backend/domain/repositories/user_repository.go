@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"time"
+
 	"devfolio-backend/domain/entities"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -9,9 +11,30 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *entities.User) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.User, error)
+	// GetByEmail prefers the exact address and falls back to the canonical
+	// key, so a.b+x@gmail.com finds the account of ab@gmail.com.
 	GetByEmail(ctx context.Context, email string) (*entities.User, error)
+	GetByIdentity(ctx context.Context, provider, subject string) (*entities.User, error)
 	Update(ctx context.Context, id primitive.ObjectID, user *entities.User) error
 	UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error
+	// UpdateKnownDevices replaces the user's device history and nothing else.
+	UpdateKnownDevices(ctx context.Context, id primitive.ObjectID, devices []entities.KnownDevice) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	// EmailExists also matches active accounts whose address has the same
+	// canonical key, so a.b+x@gmail.com counts as taken by ab@gmail.com.
 	EmailExists(ctx context.Context, email string) (bool, error)
-}
\ No newline at end of file
+	// The methods below also see deactivated users
+	GetByIDIncludingInactive(ctx context.Context, id primitive.ObjectID) (*entities.User, error)
+	List(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.User, error)
+	SetActive(ctx context.Context, id primitive.ObjectID, active bool) error
+	// GetPendingDeletionByEmail finds a deleted account that can still be
+	// restored, matching the address like GetByEmail.
+	GetPendingDeletionByEmail(ctx context.Context, email string) (*entities.User, error)
+	// DeactivatedEmailExists reports whether an account an admin deactivated
+	// holds the address or its canonical key. Deleted accounts do not count.
+	DeactivatedEmailExists(ctx context.Context, email string) (bool, error)
+	// GetDueForDeletion lists deleted accounts whose grace period ended before cutoff.
+	GetDueForDeletion(ctx context.Context, cutoff time.Time) ([]*entities.User, error)
+	// Purge removes the user document for good, unlike Delete.
+	Purge(ctx context.Context, id primitive.ObjectID) error
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// breachedPasswordList looks passwords up in an offline copy of the Have I Been
+// Pwned password hashes. The path is either a directory of range files named
+// after the first five hex digits of the SHA-1 hash (as written by the HIBP
+// downloader), each holding "SUFFIX:COUNT" lines, or a single file of
+// "HASH:COUNT" lines ordered by hash, which is binary searched on disk.
+type breachedPasswordList struct {
+	path  string
+	isDir bool
+}
+
+func openBreachedPasswordList(path string) (*breachedPasswordList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+
+	return &breachedPasswordList{path: path, isDir: info.IsDir()}, nil
+}
+
+func (l *breachedPasswordList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	if l.isDir {
+		return l.containsInRange(hash)
+	}
+	return l.containsInSortedFile(hash)
+}
+
+func (l *breachedPasswordList) containsInRange(hash string) (bool, error) {
+	prefix, suffix := hash[:5], hash[5:]
+
+	var data []byte
+	var err error
+	for _, name := range []string{prefix + ".txt", prefix, strings.ToLower(prefix) + ".txt"} {
+		data, err = os.ReadFile(filepath.Join(l.path, name))
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (l *breachedPasswordList) containsInSortedFile(hash string) (bool, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// lo is always the start of a line; every line starting before lo sorts
+	// below hash and every line starting at or after hi sorts above it.
+	lo, hi := int64(0), info.Size()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start := lo
+		if mid > lo {
+			if start, err = nextLineStart(f, mid, hi); err != nil {
+				return false, err
+			}
+			if start >= hi {
+				hi = mid
+				continue
+			}
+		}
+
+		candidate, end, err := readHashLine(f, start)
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.Compare(strings.ToUpper(candidate), hash) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = end
+		default:
+			hi = start
+		}
+	}
+
+	return false, nil
+}
+
+// nextLineStart returns the offset of the first line that starts at or after pos.
+func nextLineStart(f *os.File, pos, limit int64) (int64, error) {
+	buf := make([]byte, 256)
+	for offset := pos - 1; offset < limit; offset += int64(len(buf)) {
+		n, err := f.ReadAt(buf, offset)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return offset + int64(i) + 1, nil
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return limit, nil
+}
+
+// readHashLine reads the hash from the line at start and returns it with the
+// offset of the following line.
+func readHashLine(f *os.File, start int64) (string, int64, error) {
+	buf := make([]byte, 128)
+	n, err := f.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+
+	line := buf[:n]
+	end := start + int64(n)
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+		end = start + int64(i) + 1
+	} else if err != io.EOF {
+		return "", 0, fmt.Errorf("breached password list has an overlong line at offset %d", start)
+	}
+
+	candidate, _, _ := strings.Cut(strings.TrimSpace(string(line)), ":")
+	return candidate, end, nil
+}
@@ -1,25 +1,54 @@
 package auth
 
 import (
+	"crypto"
 	"fmt"
+	"strings"
 	"time"
 
 	"devfolio-backend/infrastructure/config"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	AccessTokenType            = "access"
+	RefreshTokenType           = "refresh"
+	EmailVerificationTokenType = "email_verification"
+	MFAPendingTokenType        = "mfa_pending"
+	DataExportTokenType        = "data_export"
+	MagicLinkTokenType         = "magic_link"
+)
+
 type JWTManager struct {
-	secretKey        string
-	accessTokenTTL   time.Duration
-	refreshTokenTTL  time.Duration
+	secretKey       string
+	signingKey      crypto.Signer
+	signingKeyID    string
+	signingMethod   jwt.SigningMethod
+	verifyKeys      map[string]*verificationKey
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
 }
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	TokenType string `json:"typ,omitempty"`
+	// Actor is set on impersonation tokens to the admin acting as the user
+	Actor *ActorClaim `json:"act,omitempty"`
+	// ResourceID limits an action token to one object, such as a data export
+	ResourceID string `json:"rid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaim identifies who is acting on behalf of the token's subject, as in
+// the RFC 8693 "act" claim.
+type ActorClaim struct {
+	UserID string `json:"sub"`
+	Email  string `json:"email,omitempty"`
+}
+
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -36,20 +65,105 @@ func NewJWTManager(cfg *config.Config) (*JWTManager, error) {
 		return nil, fmt.Errorf("invalid refresh token expiry: %w", err)
 	}
 
-	return &JWTManager{
+	manager := &JWTManager{
 		secretKey:       cfg.JWT.Secret,
+		verifyKeys:      make(map[string]*verificationKey),
 		accessTokenTTL:  accessTTL,
 		refreshTokenTTL: refreshTTL,
-	}, nil
+	}
+
+	if cfg.JWT.SigningKeyFile == "" {
+		// The shared secret is a development convenience. Anyone who knows it,
+		// or the default, can mint tokens, so other modes need a signing key.
+		if cfg.Server.GinMode != "debug" && cfg.Server.GinMode != "test" {
+			return nil, fmt.Errorf("a JWT signing key file is required in %s mode", cfg.Server.GinMode)
+		}
+		return manager, nil
+	}
+
+	// With a signing key configured, tokens are signed with it and verified by
+	// kid against it and any retired keys that are still being phased out.
+	signingKey, err := loadPrivateKeyFile(cfg.JWT.SigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT signing key: %w", err)
+	}
+	current, err := newVerificationKey(signingKey.Public())
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT signing key: %w", err)
+	}
+	manager.signingKey = signingKey
+	manager.signingKeyID = current.kid
+	manager.signingMethod = current.method
+	manager.verifyKeys[current.kid] = current
+
+	for _, path := range strings.Split(cfg.JWT.VerificationKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		publicKey, err := loadPublicKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT verification key: %w", err)
+		}
+		key, err := newVerificationKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT verification key %s: %w", path, err)
+		}
+		manager.verifyKeys[key.kid] = key
+	}
+
+	return manager, nil
 }
 
-func (j *JWTManager) GenerateTokenPair(userID, email string) (*TokenPair, error) {
-	accessToken, err := j.generateToken(userID, email, j.accessTokenTTL)
+// UsesSharedSecret reports whether tokens are signed with the HS256 secret
+// because no signing key file is configured.
+func (j *JWTManager) UsesSharedSecret() bool {
+	return j.signingKey == nil
+}
+
+// JWKS returns the public keys tokens are verified against. It is empty when
+// the shared secret is in use, since that cannot be published.
+func (j *JWTManager) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(j.verifyKeys))}
+
+	// Current signing key first, then the retired ones
+	if key, ok := j.verifyKeys[j.signingKeyID]; ok {
+		if jwk, err := publicJWK(key.publicKey); err == nil {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+	for kid, key := range j.verifyKeys {
+		if kid == j.signingKeyID {
+			continue
+		}
+		if jwk, err := publicJWK(key.publicKey); err == nil {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+
+	return set
+}
+
+// GenerateTokenPair issues an access and refresh token bound to the given
+// session. Only the access token carries the role; refreshing reads it again.
+func (j *JWTManager) GenerateTokenPair(userID, email, role, sessionID string) (*TokenPair, error) {
+	accessToken, _, err := j.generateToken(Claims{
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		TokenType: AccessTokenType,
+	}, j.accessTokenTTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := j.generateToken(userID, email, j.refreshTokenTTL)
+	refreshToken, _, err := j.generateToken(Claims{
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
+		TokenType: RefreshTokenType,
+	}, j.refreshTokenTTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -60,28 +174,86 @@ func (j *JWTManager) GenerateTokenPair(userID, email string) (*TokenPair, error)
 	}, nil
 }
 
-func (j *JWTManager) generateToken(userID, email string, ttl time.Duration) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+// GenerateImpersonationToken issues an access token for the user that carries
+// the acting admin in its act claim. There is no refresh token, so it cannot
+// outlive ttl.
+func (j *JWTManager) GenerateImpersonationToken(userID, email, role, sessionID string, actor ActorClaim, ttl time.Duration) (string, error) {
+	token, _, err := j.generateToken(Claims{
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		TokenType: AccessTokenType,
+		Actor:     &actor,
+	}, ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+
+	return token, nil
+}
+
+// GenerateActionToken issues a short-lived token that is only good for one
+// purpose, such as verifying an email address. The token ID is returned so the
+// caller can make the token single-use.
+func (j *JWTManager) GenerateActionToken(userID, email, tokenType string, ttl time.Duration) (string, string, error) {
+	return j.generateToken(Claims{
+		UserID:    userID,
+		Email:     email,
+		TokenType: tokenType,
+	}, ttl)
+}
+
+// GenerateResourceToken issues an action token that is only good for the
+// object with resourceID. Callers must compare the claim with the object the
+// token is presented for.
+func (j *JWTManager) GenerateResourceToken(userID, resourceID, tokenType string, ttl time.Duration) (string, error) {
+	token, _, err := j.generateToken(Claims{
+		UserID:     userID,
+		TokenType:  tokenType,
+		ResourceID: resourceID,
+	}, ttl)
+	return token, err
+}
+
+// ValidateActionToken validates a token issued by GenerateActionToken for the given purpose.
+func (j *JWTManager) ValidateActionToken(tokenString, tokenType string) (*Claims, error) {
+	return j.validateTokenType(tokenString, tokenType)
+}
+
+func (j *JWTManager) generateToken(claims Claims, ttl time.Duration) (string, string, error) {
+	// A random ID keeps tokens issued within the same second distinct,
+	// which refresh token rotation depends on.
+	tokenID, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        tokenID,
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+
+	var signed string
+	if j.UsesSharedSecret() {
+		signed, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.secretKey))
+	} else {
+		token := jwt.NewWithClaims(j.signingMethod, claims)
+		token.Header["kid"] = j.signingKeyID
+		signed, err = token.SignedString(j.signingKey)
+	}
+	if err != nil {
+		return "", "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	return signed, tokenID, nil
 }
 
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(j.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.keyFunc)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -94,19 +266,50 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-func (j *JWTManager) RefreshAccessToken(refreshToken string) (string, error) {
-	claims, err := j.ValidateToken(refreshToken)
-	if err != nil {
-		return "", fmt.Errorf("invalid refresh token: %w", err)
+// keyFunc resolves the key a token must be verified with. Once signing keys
+// are configured the shared secret is never accepted, so tokens forged with a
+// leaked or default secret are rejected.
+func (j *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if j.UsesSharedSecret() {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(j.secretKey), nil
 	}
 
-	// Generate new access token
-	accessToken, err := j.generateToken(claims.UserID, claims.Email, j.accessTokenTTL)
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.publicKey, nil
+}
+
+// ValidateAccessToken validates a token and rejects anything that is not an access token.
+func (j *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return j.validateTokenType(tokenString, AccessTokenType)
+}
+
+// ValidateRefreshToken validates a token and rejects anything that is not a refresh token.
+func (j *JWTManager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return j.validateTokenType(tokenString, RefreshTokenType)
+}
+
+func (j *JWTManager) validateTokenType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate new access token: %w", err)
+		return nil, err
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, fmt.Errorf("unexpected token type: %q", claims.TokenType)
 	}
 
-	return accessToken, nil
+	return claims, nil
 }
 
 func (j *JWTManager) GetAccessTokenTTL() time.Duration {
@@ -115,4 +318,4 @@ func (j *JWTManager) GetAccessTokenTTL() time.Duration {
 
 func (j *JWTManager) GetRefreshTokenTTL() time.Duration {
 	return j.refreshTokenTTL
-}
\ No newline at end of file
+}
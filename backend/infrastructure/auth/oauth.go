@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/infrastructure/config"
+)
+
+// OAuthProvider is an external identity provider users can sign in with.
+type OAuthProvider interface {
+	// Name is the provider key used in /auth/:provider routes.
+	Name() string
+	// AuthCodeURL returns the provider page the browser is sent to.
+	AuthCodeURL(ctx context.Context, flow *OAuthFlow) (string, error)
+	// Exchange trades an authorization code for the user's profile.
+	Exchange(ctx context.Context, code string, flow *OAuthFlow) (*entities.ExternalProfile, error)
+}
+
+// OAuthFlow holds the per-login secrets. All three travel together in the
+// state cookie, so the PKCE verifier and the nonce are bound to the state the
+// callback is checked against.
+type OAuthFlow struct {
+	State        string
+	CodeVerifier string
+	Nonce        string
+}
+
+// NewOAuthFlow generates a fresh state, PKCE code verifier and nonce.
+func NewOAuthFlow() (*OAuthFlow, error) {
+	var values [3]string
+	for i := range values {
+		value, err := GenerateRandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return &OAuthFlow{State: values[0], CodeVerifier: values[1], Nonce: values[2]}, nil
+}
+
+// ParseOAuthFlow reads a flow back from its Encode form.
+func ParseOAuthFlow(encoded string) (*OAuthFlow, error) {
+	parts := strings.Split(encoded, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid login state")
+	}
+
+	return &OAuthFlow{State: parts[0], CodeVerifier: parts[1], Nonce: parts[2]}, nil
+}
+
+// Encode serializes the flow for the state cookie. The values are base64url,
+// which never contains a dot.
+func (f *OAuthFlow) Encode() string {
+	return f.State + "." + f.CodeVerifier + "." + f.Nonce
+}
+
+// CodeChallenge is the S256 PKCE challenge for the code verifier.
+func (f *OAuthFlow) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(f.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OAuthRegistry holds the providers that are configured with client credentials.
+type OAuthRegistry struct {
+	providers   map[string]OAuthProvider
+	frontendURL string
+}
+
+func NewOAuthRegistry(cfg *config.Config) (*OAuthRegistry, error) {
+	registry := &OAuthRegistry{
+		providers:   make(map[string]OAuthProvider),
+		frontendURL: strings.TrimRight(cfg.CORS.FrontendURL, "/"),
+	}
+
+	providers := make(map[string]config.OAuthProviderConfig, len(cfg.OAuth)+1)
+	for name, providerCfg := range cfg.OAuth {
+		providers[strings.ToLower(name)] = providerCfg
+	}
+	// The google section predates the generic provider list
+	if _, ok := providers["google"]; !ok {
+		providers["google"] = config.OAuthProviderConfig{
+			ClientID:     cfg.Google.ClientID,
+			ClientSecret: cfg.Google.ClientSecret,
+			RedirectURL:  cfg.Google.RedirectURL,
+		}
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	for name, providerCfg := range providers {
+		if providerCfg.ClientID == "" || providerCfg.ClientSecret == "" {
+			continue
+		}
+		if providerCfg.RedirectURL == "" {
+			providerCfg.RedirectURL = fmt.Sprintf("http://localhost:%s/api/v1/auth/%s/callback", cfg.Server.Port, name)
+		}
+
+		provider, err := newOAuthProvider(name, providerCfg, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s login provider: %w", name, err)
+		}
+		registry.providers[name] = provider
+	}
+
+	return registry, nil
+}
+
+func newOAuthProvider(name string, cfg config.OAuthProviderConfig, httpClient *http.Client) (OAuthProvider, error) {
+	providerType := cfg.Type
+	if providerType == "" {
+		providerType = name
+	}
+
+	client := oauthClient{
+		name:         name,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		redirectURL:  cfg.RedirectURL,
+		scopes:       strings.Fields(cfg.Scopes),
+		authURL:      cfg.AuthURL,
+		tokenURL:     cfg.TokenURL,
+		httpClient:   httpClient,
+	}
+
+	switch providerType {
+	case "github":
+		return newGitHubProvider(client, cfg), nil
+	case "google":
+		return newOIDCProvider(client, cfg, "https://accounts.google.com"), nil
+	case "gitlab":
+		return newOIDCProvider(client, cfg, "https://gitlab.com"), nil
+	case "microsoft":
+		return newOIDCProvider(client, cfg, "https://login.microsoftonline.com/common/v2.0"), nil
+	case "oidc":
+		if cfg.Issuer == "" {
+			return nil, fmt.Errorf("oidc providers need an issuer")
+		}
+		return newOIDCProvider(client, cfg, ""), nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", providerType)
+	}
+}
+
+// Get returns the named provider if it is configured.
+func (r *OAuthRegistry) Get(name string) (OAuthProvider, bool) {
+	provider, ok := r.providers[strings.ToLower(name)]
+	return provider, ok
+}
+
+// Names lists the configured providers in alphabetical order.
+func (r *OAuthRegistry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FrontendCallbackURL is where the browser is sent once a provider login finishes.
+func (r *OAuthRegistry) FrontendCallbackURL(provider string, success bool, message string) string {
+	if success {
+		return r.FrontendStatusURL(provider, "success", "")
+	}
+	return r.FrontendStatusURL(provider, "error", message)
+}
+
+// FrontendStatusURL is FrontendCallbackURL with any status, such as "linked"
+// or "link_required".
+func (r *OAuthRegistry) FrontendStatusURL(provider, status, message string) string {
+	values := url.Values{}
+	values.Set("status", status)
+	if message != "" {
+		values.Set("message", message)
+	}
+	return r.frontendURL + "/auth/" + url.PathEscape(strings.ToLower(provider)) + "/callback?" + values.Encode()
+}
+
+// oauthClient holds what every authorization code flow needs, whatever the provider.
+type oauthClient struct {
+	name         string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	authURL      string
+	tokenURL     string
+	httpClient   *http.Client
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+}
+
+func (o *oauthClient) Name() string {
+	return o.name
+}
+
+func (o *oauthClient) authCodeURL(authURL string, flow *OAuthFlow, extra url.Values) string {
+	values := url.Values{}
+	values.Set("client_id", o.clientID)
+	values.Set("redirect_uri", o.redirectURL)
+	values.Set("response_type", "code")
+	values.Set("scope", strings.Join(o.scopes, " "))
+	values.Set("state", flow.State)
+	values.Set("code_challenge", flow.CodeChallenge())
+	values.Set("code_challenge_method", "S256")
+	for key, value := range extra {
+		values[key] = value
+	}
+
+	separator := "?"
+	if strings.Contains(authURL, "?") {
+		separator = "&"
+	}
+	return authURL + separator + values.Encode()
+}
+
+func (o *oauthClient) exchangeCode(ctx context.Context, tokenURL, code string, flow *OAuthFlow) (*oauthTokenResponse, error) {
+	form := url.Values{}
+	form.Set("code", code)
+	form.Set("code_verifier", flow.CodeVerifier)
+	form.Set("client_id", o.clientID)
+	form.Set("client_secret", o.clientSecret)
+	form.Set("redirect_uri", o.redirectURL)
+	form.Set("grant_type", "authorization_code")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token oauthTokenResponse
+	if err := o.doJSON(req, &token); err != nil {
+		return nil, fmt.Errorf("failed to exchange %s code: %w", o.name, err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("%s token exchange failed: %s", o.name, token.Error)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("%s did not return an access token", o.name)
+	}
+
+	return &token, nil
+}
+
+// getJSON fetches an API resource with the user's access token.
+func (o *oauthClient) getJSON(ctx context.Context, resourceURL, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", o.name, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	return o.doJSON(req, out)
+}
+
+func (o *oauthClient) doJSON(req *http.Request, out interface{}) error {
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s responded with status %d", req.URL.Host, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", req.URL.Host, err)
+	}
+
+	return nil
+}
+
+// splitName splits a display name into first and last name for providers that
+// only return one name field.
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
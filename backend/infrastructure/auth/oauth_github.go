@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/infrastructure/config"
+)
+
+// gitHubProvider signs users in with GitHub, which speaks plain OAuth 2.0
+// rather than OpenID Connect.
+type gitHubProvider struct {
+	oauthClient
+	userURL   string
+	emailsURL string
+}
+
+type gitHubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type gitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func newGitHubProvider(client oauthClient, cfg config.OAuthProviderConfig) *gitHubProvider {
+	if client.authURL == "" {
+		client.authURL = "https://github.com/login/oauth/authorize"
+	}
+	if client.tokenURL == "" {
+		client.tokenURL = "https://github.com/login/oauth/access_token"
+	}
+	if len(client.scopes) == 0 {
+		client.scopes = []string{"read:user", "user:email"}
+	}
+
+	userURL := cfg.UserInfoURL
+	if userURL == "" {
+		userURL = "https://api.github.com/user"
+	}
+
+	return &gitHubProvider{
+		oauthClient: client,
+		userURL:     userURL,
+		emailsURL:   strings.TrimRight(userURL, "/") + "/emails",
+	}
+}
+
+func (g *gitHubProvider) AuthCodeURL(_ context.Context, flow *OAuthFlow) (string, error) {
+	return g.authCodeURL(g.authURL, flow, nil), nil
+}
+
+func (g *gitHubProvider) Exchange(ctx context.Context, code string, flow *OAuthFlow) (*entities.ExternalProfile, error) {
+	token, err := g.exchangeCode(ctx, g.tokenURL, code, flow)
+	if err != nil {
+		return nil, err
+	}
+
+	var user gitHubUser
+	if err := g.getJSON(ctx, g.userURL, token.AccessToken, &user); err != nil {
+		return nil, fmt.Errorf("failed to fetch github user profile: %w", err)
+	}
+
+	// The profile email is optional and may be unverified, so use the primary verified address
+	var emails []gitHubEmail
+	if err := g.getJSON(ctx, g.emailsURL, token.AccessToken, &emails); err != nil {
+		return nil, fmt.Errorf("failed to fetch github email addresses: %w", err)
+	}
+
+	profile := &entities.ExternalProfile{
+		Provider: g.name,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Avatar:   user.AvatarURL,
+	}
+	for _, email := range emails {
+		if email.Primary {
+			profile.Email = strings.ToLower(email.Email)
+			profile.EmailVerified = email.Verified
+			break
+		}
+	}
+
+	profile.FirstName, profile.LastName = splitName(user.Name)
+	if profile.FirstName == "" {
+		profile.FirstName = user.Login
+	}
+
+	return profile, nil
+}
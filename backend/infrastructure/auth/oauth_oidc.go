@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/infrastructure/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcProvider signs users in with any OpenID Connect issuer. Endpoints and
+// signing keys come from the issuer's discovery document, and the user is
+// identified by the ID token rather than by an access token.
+type oidcProvider struct {
+	oauthClient
+	issuer      string
+	userInfoURL string
+	trustEmail  bool
+
+	mu             sync.Mutex
+	discovered     bool
+	expectedIssuer string
+	keys           *jwksCache
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcClaims struct {
+	Nonce           string      `json:"nonce"`
+	AuthorizedParty string      `json:"azp"`
+	TenantID        string      `json:"tid"`
+	Email           string      `json:"email"`
+	EmailVerified   interface{} `json:"email_verified"`
+	Name            string      `json:"name"`
+	GivenName       string      `json:"given_name"`
+	FamilyName      string      `json:"family_name"`
+	Picture         string      `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+type oidcUserInfo struct {
+	Subject       string      `json:"sub"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+}
+
+func newOIDCProvider(client oauthClient, cfg config.OAuthProviderConfig, defaultIssuer string) *oidcProvider {
+	issuer := cfg.Issuer
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+	if len(client.scopes) == 0 {
+		client.scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &oidcProvider{
+		oauthClient: client,
+		issuer:      strings.TrimRight(issuer, "/"),
+		userInfoURL: cfg.UserInfoURL,
+		trustEmail:  cfg.TrustEmail,
+	}
+}
+
+// discover loads endpoints, the expected issuer and the JWKS location from the
+// discovery document. It runs on first use rather than at startup so an
+// unreachable issuer does not stop the server, and it is retried until it succeeds.
+func (p *oidcProvider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered {
+		return nil
+	}
+
+	var doc oidcDiscovery
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return fmt.Errorf("failed to discover %s endpoints: %w", p.name, err)
+	}
+	if doc.Issuer == "" || doc.JWKSURI == "" {
+		return fmt.Errorf("%s discovery document is missing the issuer or jwks_uri", p.name)
+	}
+
+	if p.authURL == "" {
+		p.authURL = doc.AuthorizationEndpoint
+	}
+	if p.tokenURL == "" {
+		p.tokenURL = doc.TokenEndpoint
+	}
+	if p.userInfoURL == "" {
+		p.userInfoURL = doc.UserInfoEndpoint
+	}
+	if p.authURL == "" || p.tokenURL == "" {
+		return fmt.Errorf("%s discovery document is missing endpoints", p.name)
+	}
+
+	p.expectedIssuer = doc.Issuer
+	p.keys = newJWKSCache(doc.JWKSURI, p.httpClient)
+	p.discovered = true
+	return nil
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, flow *OAuthFlow) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+	return p.authCodeURL(p.authURL, flow, url.Values{"nonce": {flow.Nonce}}), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string, flow *OAuthFlow) (*entities.ExternalProfile, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	token, err := p.exchangeCode(ctx, p.tokenURL, code, flow)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%s did not return an id token", p.name)
+	}
+
+	claims, err := p.verifyIDToken(ctx, token.IDToken, flow.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &entities.ExternalProfile{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: p.trustEmail || claimIsTrue(claims.EmailVerified),
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+		Avatar:        claims.Picture,
+	}
+	if profile.FirstName == "" && profile.LastName == "" {
+		profile.FirstName, profile.LastName = splitName(claims.Name)
+	}
+
+	// Some issuers leave the email out of the ID token; take it from userinfo,
+	// but only when it describes the same subject
+	if profile.Email == "" && p.userInfoURL != "" {
+		var info oidcUserInfo
+		if err := p.getJSON(ctx, p.userInfoURL, token.AccessToken, &info); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s user profile: %w", p.name, err)
+		}
+		if info.Subject != claims.Subject {
+			return nil, fmt.Errorf("%s userinfo subject does not match the id token", p.name)
+		}
+		profile.Email = strings.ToLower(info.Email)
+		profile.EmailVerified = p.trustEmail || claimIsTrue(info.EmailVerified)
+	}
+
+	return profile, nil
+}
+
+// verifyIDToken checks the ID token signature against the issuer's published
+// keys, and its issuer, audience, expiry and nonce.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, idToken, nonce string) (*oidcClaims, error) {
+	claims := &oidcClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.Key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s id token: %w", p.name, err)
+	}
+
+	// Multi-tenant Microsoft metadata uses a {tenantid} placeholder in the issuer
+	expectedIssuer := strings.ReplaceAll(p.expectedIssuer, "{tenantid}", claims.TenantID)
+	if claims.Issuer != expectedIssuer {
+		return nil, fmt.Errorf("invalid %s id token: unexpected issuer %q", p.name, claims.Issuer)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientID {
+		return nil, fmt.Errorf("invalid %s id token: issued to another client", p.name)
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("invalid %s id token: nonce mismatch", p.name)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("invalid %s id token: missing subject", p.name)
+	}
+
+	return claims, nil
+}
+
+// claimIsTrue accepts booleans and the "true" strings some issuers send instead.
+func claimIsTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
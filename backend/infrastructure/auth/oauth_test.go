@@ -0,0 +1,577 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"devfolio-backend/infrastructure/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	fakeClientID     = "devfolio-client"
+	fakeClientSecret = "devfolio-secret"
+	fakeRedirectURL  = "https://api.devfolio.test/api/v1/auth/fake/callback"
+)
+
+// fakeIdentityProvider is a minimal OpenID Connect issuer, plus GitHub's user
+// API, for exercising the provider flows end to end.
+type fakeIdentityProvider struct {
+	*httptest.Server
+	t       *testing.T
+	signer  ed25519.PrivateKey
+	kid     string
+	issuer  string
+	profile map[string]interface{}
+	// githubEmails is served at /user/emails
+	githubEmails []gitHubEmail
+	// discovery is served at /.well-known/openid-configuration when set
+	discovery map[string]string
+
+	mu             sync.Mutex
+	grants         map[string]fakeGrant
+	discoveryCalls int
+	userInfoCalls  int
+}
+
+type fakeGrant struct {
+	codeChallenge string
+	nonce         string
+}
+
+func newFakeIdentityProvider(t *testing.T) *fakeIdentityProvider {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := publicJWK(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &fakeIdentityProvider{
+		t:      t,
+		signer: private,
+		kid:    jwk.KeyID,
+		grants: make(map[string]fakeGrant),
+		profile: map[string]interface{}{
+			"sub":            "user-123",
+			"email":          "Ada@Example.com",
+			"email_verified": true,
+			"given_name":     "Ada",
+			"family_name":    "Lovelace",
+			"picture":        "https://idp.test/ada.png",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		idp.discoveryCalls++
+		idp.mu.Unlock()
+
+		doc := idp.discovery
+		if doc == nil {
+			doc = map[string]string{
+				"issuer":                 idp.URL,
+				"authorization_endpoint": idp.URL + "/authorize",
+				"token_endpoint":         idp.URL + "/token",
+				"userinfo_endpoint":      idp.URL + "/userinfo",
+				"jwks_uri":               idp.URL + "/jwks",
+			}
+		}
+		writeJSON(w, http.StatusOK, doc)
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, JWKSet{Keys: []JWK{*jwk}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/login/oauth/access_token", idp.token)
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		idp.userInfoCalls++
+		idp.mu.Unlock()
+
+		if !idp.authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"sub":            "user-123",
+			"email":          "userinfo@example.com",
+			"email_verified": "true",
+		})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if !idp.authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":         583231,
+			"login":      "octocat",
+			"name":       "Mona Lisa Octocat",
+			"email":      "public@example.com",
+			"avatar_url": "https://github.test/octocat.png",
+		})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		if !idp.authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+		writeJSON(w, http.StatusOK, idp.githubEmails)
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	idp.issuer = idp.URL
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+// authorize stands in for the user approving the login on the provider's
+// page. It checks the authorization URL and returns the code the provider
+// would redirect back with.
+func (idp *fakeIdentityProvider) authorize(authURL string, flow *OAuthFlow) string {
+	idp.t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	query := parsed.Query()
+	for key, want := range map[string]string{
+		"client_id":             fakeClientID,
+		"redirect_uri":          fakeRedirectURL,
+		"response_type":         "code",
+		"state":                 flow.State,
+		"code_challenge":        flow.CodeChallenge(),
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(key); got != want {
+			idp.t.Fatalf("authorization URL %s = %q, want %q", key, got, want)
+		}
+	}
+
+	code, err := GenerateRandomToken(16)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.grants[code] = fakeGrant{codeChallenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+
+	return code
+}
+
+func (idp *fakeIdentityProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != fakeClientID ||
+		r.PostForm.Get("client_secret") != fakeClientSecret ||
+		r.PostForm.Get("redirect_uri") != fakeRedirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	grant, ok := idp.grants[r.PostForm.Get("code")]
+	delete(idp.grants, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	// PKCE: the verifier must hash to the challenge sent with the authorization request
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   idp.issuer,
+		"aud":   fakeClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": grant.nonce,
+	}
+	for key, value := range idp.profile {
+		claims[key] = value
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "access-for-user-123",
+		"token_type":   "Bearer",
+		"id_token":     idp.sign(claims),
+	})
+}
+
+func (idp *fakeIdentityProvider) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.signer)
+	if err != nil {
+		// Runs on the server goroutine, where Fatal is not allowed
+		idp.t.Errorf("failed to sign id token: %v", err)
+	}
+	return signed
+}
+
+func (idp *fakeIdentityProvider) authorized(r *http.Request) bool {
+	return r.Header.Get("Authorization") == "Bearer access-for-user-123"
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newFakeProvider(t *testing.T, idp *fakeIdentityProvider, cfg config.OAuthProviderConfig) OAuthProvider {
+	t.Helper()
+
+	cfg.ClientID = fakeClientID
+	cfg.ClientSecret = fakeClientSecret
+	cfg.RedirectURL = fakeRedirectURL
+	provider, err := newOAuthProvider("fake", cfg, idp.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// signIn starts a login and approves it at the fake provider, returning the
+// flow and the code to exchange.
+func signIn(t *testing.T, idp *fakeIdentityProvider, provider OAuthProvider) (*OAuthFlow, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	flow, err := NewOAuthFlow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, flow)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	return flow, idp.authorize(authURL, flow)
+}
+
+func TestOIDCProviderSignIn(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeIdentityProvider(t)
+	provider := newFakeProvider(t, idp, config.OAuthProviderConfig{Type: "oidc", Issuer: idp.URL + "/"})
+
+	flow, err := NewOAuthFlow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, flow)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	// The endpoints come from discovery, and the nonce and scopes are sent
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+		t.Fatalf("authorization URL = %s, want the discovered endpoint", authURL)
+	}
+	parsed, _ := url.Parse(authURL)
+	if parsed.Query().Get("nonce") != flow.Nonce {
+		t.Errorf("nonce = %q, want %q", parsed.Query().Get("nonce"), flow.Nonce)
+	}
+	if parsed.Query().Get("scope") != "openid email profile" {
+		t.Errorf("scope = %q, want the OIDC defaults", parsed.Query().Get("scope"))
+	}
+
+	profile, err := provider.Exchange(ctx, idp.authorize(authURL, flow), flow)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if profile.Provider != "fake" || profile.Subject != "user-123" || profile.Email != "ada@example.com" ||
+		!profile.EmailVerified || profile.FirstName != "Ada" || profile.LastName != "Lovelace" {
+		t.Errorf("profile = %+v", profile)
+	}
+
+	// Discovery runs once per provider
+	signIn(t, idp, provider)
+	if idp.discoveryCalls != 1 {
+		t.Errorf("discovery fetched %d times, want 1", idp.discoveryCalls)
+	}
+	if idp.userInfoCalls != 0 {
+		t.Errorf("userinfo fetched %d times although the id token had an email", idp.userInfoCalls)
+	}
+}
+
+func TestOIDCProviderDiscoveryErrors(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeIdentityProvider(t)
+	idp.discovery = map[string]string{"issuer": idp.URL, "authorization_endpoint": idp.URL + "/authorize"}
+	provider := newFakeProvider(t, idp, config.OAuthProviderConfig{Type: "oidc", Issuer: idp.URL})
+
+	flow, _ := NewOAuthFlow()
+	if _, err := provider.AuthCodeURL(ctx, flow); err == nil || !strings.Contains(err.Error(), "jwks_uri") {
+		t.Fatalf("AuthCodeURL() error = %v, want missing jwks_uri", err)
+	}
+
+	// A failed discovery is retried on the next login
+	idp.discovery = nil
+	if _, err := provider.AuthCodeURL(ctx, flow); err != nil {
+		t.Fatalf("AuthCodeURL() error = %v after discovery recovered", err)
+	}
+	if idp.discoveryCalls != 2 {
+		t.Errorf("discovery fetched %d times, want 2", idp.discoveryCalls)
+	}
+}
+
+func TestOIDCProviderRequiresCodeVerifier(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeIdentityProvider(t)
+	provider := newFakeProvider(t, idp, config.OAuthProviderConfig{Type: "oidc", Issuer: idp.URL})
+
+	flow, code := signIn(t, idp, provider)
+
+	// A code stolen from the redirect is useless without the verifier
+	other, _ := NewOAuthFlow()
+	stolen := &OAuthFlow{State: flow.State, CodeVerifier: other.CodeVerifier, Nonce: flow.Nonce}
+	if _, err := provider.Exchange(ctx, code, stolen); err == nil {
+		t.Fatal("Exchange() succeeded with the wrong code verifier")
+	}
+}
+
+func TestOIDCProviderRejectsIDTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(idp *fakeIdentityProvider)
+		nonce   string
+		wantErr string
+	}{
+		{
+			name:    "wrong issuer",
+			modify:  func(idp *fakeIdentityProvider) { idp.issuer = "https://evil.test" },
+			wantErr: "unexpected issuer",
+		},
+		{
+			name:    "wrong audience",
+			modify:  func(idp *fakeIdentityProvider) { idp.profile["aud"] = "another-client" },
+			wantErr: "audience",
+		},
+		{
+			name: "several audiences without azp",
+			modify: func(idp *fakeIdentityProvider) {
+				idp.profile["aud"] = []string{fakeClientID, "another-client"}
+			},
+			wantErr: "issued to another client",
+		},
+		{
+			name:    "wrong nonce",
+			nonce:   "replayed-nonce",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "expired",
+			modify:  func(idp *fakeIdentityProvider) { idp.profile["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: "expired",
+		},
+		{
+			name:    "missing subject",
+			modify:  func(idp *fakeIdentityProvider) { delete(idp.profile, "sub") },
+			wantErr: "missing subject",
+		},
+		{
+			name: "unknown signing key",
+			modify: func(idp *fakeIdentityProvider) {
+				_, other, _ := ed25519.GenerateKey(rand.Reader)
+				idp.signer = other
+				idp.kid = "rotated-away"
+			},
+			wantErr: "unknown signing key",
+		},
+		{
+			name: "bad signature",
+			modify: func(idp *fakeIdentityProvider) {
+				_, other, _ := ed25519.GenerateKey(rand.Reader)
+				idp.signer = other
+			},
+			wantErr: "signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdentityProvider(t)
+			provider := newFakeProvider(t, idp, config.OAuthProviderConfig{Type: "oidc", Issuer: idp.URL})
+			if tt.modify != nil {
+				tt.modify(idp)
+			}
+
+			flow, code := signIn(t, idp, provider)
+			if tt.nonce != "" {
+				flow.Nonce = tt.nonce
+			}
+
+			_, err := provider.Exchange(context.Background(), code, flow)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Exchange() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderUserInfoFallback(t *testing.T) {
+	idp := newFakeIdentityProvider(t)
+	delete(idp.profile, "email")
+	delete(idp.profile, "email_verified")
+	provider := newFakeProvider(t, idp, config.OAuthProviderConfig{Type: "oidc", Issuer: idp.URL})
+
+	flow, code := signIn(t, idp, provider)
+	profile, err := provider.Exchange(context.Background(), code, flow)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if profile.Email != "userinfo@example.com" || !profile.EmailVerified {
+		t.Errorf("profile = %+v, want the userinfo email", profile)
+	}
+
+	// Userinfo about someone else is ignored
+	idp.profile["sub"] = "user-456"
+	flow, code = signIn(t, idp, provider)
+	if _, err := provider.Exchange(context.Background(), code, flow); err == nil || !strings.Contains(err.Error(), "subject does not match") {
+		t.Fatalf("Exchange() error = %v, want subject mismatch", err)
+	}
+}
+
+func TestOIDCProviderEmailVerification(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		verified   interface{}
+		trustEmail bool
+		want       bool
+	}{
+		{name: "verified", verified: true, want: true},
+		{name: "verified as string", verified: "true", want: true},
+		{name: "unverified", verified: false, want: false},
+		{name: "missing", verified: nil, want: false},
+		{name: "trusted issuer", verified: nil, trustEmail: true, want: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdentityProvider(t)
+			idp.profile["email_verified"] = tt.verified
+			provider := newFakeProvider(t, idp, config.OAuthProviderConfig{Type: "oidc", Issuer: idp.URL, TrustEmail: tt.trustEmail})
+
+			flow, code := signIn(t, idp, provider)
+			profile, err := provider.Exchange(context.Background(), code, flow)
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if profile.EmailVerified != tt.want {
+				t.Errorf("EmailVerified = %v, want %v", profile.EmailVerified, tt.want)
+			}
+		})
+	}
+}
+
+func newFakeGitHubProvider(t *testing.T, idp *fakeIdentityProvider) OAuthProvider {
+	return newFakeProvider(t, idp, config.OAuthProviderConfig{
+		Type:        "github",
+		AuthURL:     idp.URL + "/login/oauth/authorize",
+		TokenURL:    idp.URL + "/login/oauth/access_token",
+		UserInfoURL: idp.URL + "/user",
+	})
+}
+
+func TestGitHubProviderSignIn(t *testing.T) {
+	idp := newFakeIdentityProvider(t)
+	idp.githubEmails = []gitHubEmail{
+		{Email: "old@example.com", Verified: true},
+		{Email: "Mona@Example.com", Primary: true, Verified: true},
+	}
+	provider := newFakeGitHubProvider(t, idp)
+
+	flow, code := signIn(t, idp, provider)
+	profile, err := provider.Exchange(context.Background(), code, flow)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	// The public profile email is ignored in favour of the primary address from /user/emails
+	if profile.Subject != "583231" || profile.Email != "mona@example.com" || !profile.EmailVerified ||
+		profile.FirstName != "Mona" || profile.LastName != "Lisa Octocat" {
+		t.Errorf("profile = %+v", profile)
+	}
+}
+
+func TestGitHubProviderUnverifiedPrimaryEmail(t *testing.T) {
+	idp := newFakeIdentityProvider(t)
+	idp.githubEmails = []gitHubEmail{
+		{Email: "verified@example.com", Verified: true},
+		{Email: "primary@example.com", Primary: true, Verified: false},
+	}
+	provider := newFakeGitHubProvider(t, idp)
+
+	flow, code := signIn(t, idp, provider)
+	profile, err := provider.Exchange(context.Background(), code, flow)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if profile.Email != "primary@example.com" || profile.EmailVerified {
+		t.Errorf("profile = %+v, want the unverified primary address marked unverified", profile)
+	}
+}
+
+func TestGitHubProviderRequiresCodeVerifier(t *testing.T) {
+	idp := newFakeIdentityProvider(t)
+	provider := newFakeGitHubProvider(t, idp)
+
+	flow, code := signIn(t, idp, provider)
+	flow.CodeVerifier = "guessed"
+	if _, err := provider.Exchange(context.Background(), code, flow); err == nil {
+		t.Fatal("Exchange() succeeded with the wrong code verifier")
+	}
+}
+
+func TestOAuthRegistry(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: "8080"},
+		CORS:   config.CORSConfig{FrontendURL: "https://devfolio.test/"},
+		Google: config.GoogleConfig{ClientID: "google-id", ClientSecret: "google-secret"},
+		OAuth: map[string]config.OAuthProviderConfig{
+			"GitHub":    {ClientID: "github-id", ClientSecret: "github-secret"},
+			"gitlab":    {ClientID: "gitlab-id"},
+			"corporate": {Type: "oidc", ClientID: "corp-id", ClientSecret: "corp-secret", Issuer: "https://sso.corp.test"},
+		},
+	}
+
+	registry, err := NewOAuthRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(registry.Names(), ","); got != "corporate,github,google" {
+		t.Errorf("Names() = %s, want corporate,github,google", got)
+	}
+	if _, ok := registry.Get("GITHUB"); !ok {
+		t.Error("Get() is not case insensitive")
+	}
+	if want := "https://devfolio.test/auth/github/callback?message=denied&status=error"; registry.FrontendCallbackURL("github", false, "denied") != want {
+		t.Errorf("FrontendCallbackURL() = %s, want %s", registry.FrontendCallbackURL("github", false, "denied"), want)
+	}
+
+	cfg.OAuth["broken"] = config.OAuthProviderConfig{Type: "oidc", ClientID: "id", ClientSecret: "secret"}
+	if _, err := NewOAuthRegistry(cfg); err == nil {
+		t.Error("NewOAuthRegistry() accepted an oidc provider without an issuer")
+	}
+}
@@ -2,47 +2,197 @@ package auth
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
+
+	"devfolio-backend/infrastructure/config"
 	"golang.org/x/crypto/bcrypt"
 )
 
-const (
-	MinPasswordLength = 8
-	BcryptCost       = 12
-)
+// MaxPasswordBytes bounds how much input is fed to the password hash.
+const MaxPasswordBytes = 1024
+
+// PasswordPolicyError lists every rule a password broke, one message per rule.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+type PasswordManager struct {
+	policy   config.PasswordPolicyConfig
+	argon2   argon2Params
+	breached *breachedPasswordList
+}
+
+func NewPasswordManager(cfg *config.Config) (*PasswordManager, error) {
+	policy := cfg.PasswordPolicy
+	if policy.MinLength < 1 {
+		return nil, fmt.Errorf("password min length must be at least 1")
+	}
+	if policy.MaxLength < policy.MinLength || policy.MaxLength > MaxPasswordBytes {
+		return nil, fmt.Errorf("password max length must be between the min length and %d bytes", MaxPasswordBytes)
+	}
+
+	hash := cfg.PasswordHash
+	if hash.Argon2Memory < 8*uint32(hash.Argon2Parallelism) || hash.Argon2Iterations < 1 || hash.Argon2Parallelism < 1 {
+		return nil, fmt.Errorf("invalid argon2id parameters: memory must be at least 8 KiB per thread and iterations and parallelism at least 1")
+	}
 
-type PasswordManager struct{}
+	manager := &PasswordManager{
+		policy: policy,
+		argon2: argon2Params{
+			memory:      hash.Argon2Memory,
+			iterations:  hash.Argon2Iterations,
+			parallelism: hash.Argon2Parallelism,
+			saltLength:  16,
+			keyLength:   32,
+		},
+	}
+	if policy.BreachedPasswordFile != "" {
+		list, err := openBreachedPasswordList(policy.BreachedPasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		manager.breached = list
+	}
 
-func NewPasswordManager() *PasswordManager {
-	return &PasswordManager{}
+	return manager, nil
 }
 
+// HashPassword hashes a password with Argon2id and returns it in PHC format.
 func (p *PasswordManager) HashPassword(password string) (string, error) {
-	if len(password) < MinPasswordLength {
-		return "", fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+	if len(password) > MaxPasswordBytes {
+		return "", fmt.Errorf("password must be at most %d bytes long", MaxPasswordBytes)
 	}
 
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+	hashed, err := hashArgon2id(password, p.argon2)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	return string(hashedBytes), nil
+	return hashed, nil
 }
 
+// VerifyPassword checks a password against an Argon2id hash or a legacy bcrypt hash.
 func (p *PasswordManager) VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if !strings.HasPrefix(hashedPassword, argon2idPrefix) {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	}
+
+	if len(password) > MaxPasswordBytes {
+		return fmt.Errorf("password does not match")
+	}
+
+	ok, err := verifyArgon2id(hashedPassword, password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("password does not match")
+	}
+
+	return nil
 }
 
-func (p *PasswordManager) ValidatePassword(password string) error {
-	if len(password) < MinPasswordLength {
-		return fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+// NeedsRehash reports whether a stored hash is bcrypt or uses Argon2id
+// parameters other than the configured ones, so it should be replaced the
+// next time the plaintext password is available.
+func (p *PasswordManager) NeedsRehash(hashedPassword string) bool {
+	params, _, _, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params.memory != p.argon2.memory ||
+		params.iterations != p.argon2.iterations ||
+		params.parallelism != p.argon2.parallelism ||
+		params.keyLength != p.argon2.keyLength
+}
+
+// ValidatePassword checks a new password against the configured policy.
+// personal holds the user's email address and names, which the password must
+// not contain. A *PasswordPolicyError is returned when rules are broken.
+func (p *PasswordManager) ValidatePassword(password string, personal ...string) error {
+	var violations []string
+
+	if length := len([]rune(password)); length < p.policy.MinLength {
+		violations = append(violations, fmt.Sprintf("password must be at least %d characters long", p.policy.MinLength))
+	}
+	if len(password) > p.policy.MaxLength {
+		violations = append(violations, fmt.Sprintf("password must be at most %d bytes long", p.policy.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.policy.RequireUppercase && !hasUpper {
+		violations = append(violations, "password must contain an uppercase letter")
+	}
+	if p.policy.RequireLowercase && !hasLower {
+		violations = append(violations, "password must contain a lowercase letter")
+	}
+	if p.policy.RequireDigit && !hasDigit {
+		violations = append(violations, "password must contain a number")
+	}
+	if p.policy.RequireSymbol && !hasSymbol {
+		violations = append(violations, "password must contain a special character")
+	}
+
+	if p.policy.RejectPersonalInfo && containsPersonalInfo(password, personal) {
+		violations = append(violations, "password must not contain your email address or name")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	// The breach lookup reads from disk, so only do it for otherwise valid passwords
+	if p.breached != nil {
+		found, err := p.breached.Contains(password)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if found {
+			return &PasswordPolicyError{Violations: []string{"password has appeared in a data breach, choose a different one"}}
+		}
 	}
 
-	// Add more validation rules as needed
-	// - Must contain uppercase letter
-	// - Must contain lowercase letter  
-	// - Must contain number
-	// - Must contain special character
-	
 	return nil
-}
\ No newline at end of file
+}
+
+// containsPersonalInfo reports whether the password contains the email
+// address, its local part or any name. Values shorter than three characters
+// are skipped so short names do not reject most passwords.
+func containsPersonalInfo(password string, personal []string) bool {
+	lower := strings.ToLower(password)
+
+	var values []string
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		values = append(values, value)
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			values = append(values, local)
+		}
+	}
+
+	for _, value := range values {
+		if len([]rune(value)) >= 3 && strings.Contains(lower, value) {
+			return true
+		}
+	}
+
+	return false
+}
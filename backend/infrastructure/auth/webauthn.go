@@ -0,0 +1,415 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/infrastructure/config"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasskeyCeremonyTimeout is how long a browser has to complete a WebAuthn ceremony.
+const PasskeyCeremonyTimeout = 5 * time.Minute
+
+// COSE algorithm identifiers we accept, in order of preference.
+const (
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+const (
+	authDataFlagUserPresent  = 0x01
+	authDataFlagUserVerified = 0x04
+	authDataFlagAttested     = 0x40
+)
+
+// WebAuthnManager runs the server side of WebAuthn registration and
+// authentication ceremonies for passkeys. Attestation is not verified: we ask
+// for "none" because we do not restrict which authenticators users may use.
+type WebAuthnManager struct {
+	rpID    string
+	rpName  string
+	origins []string
+}
+
+type collectedClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+func NewWebAuthnManager(cfg *config.Config) *WebAuthnManager {
+	origins := []string{}
+	for _, origin := range strings.Split(cfg.WebAuthn.Origins, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = append(origins, strings.TrimRight(cfg.CORS.FrontendURL, "/"))
+	}
+
+	return &WebAuthnManager{
+		rpID:    cfg.WebAuthn.RPID,
+		rpName:  cfg.WebAuthn.RPName,
+		origins: origins,
+	}
+}
+
+// PasskeyUserHandle is the opaque WebAuthn user handle for a user. Discoverable
+// credentials hand it back during login, which is how the user is found.
+func PasskeyUserHandle(userID primitive.ObjectID) string {
+	return base64.RawURLEncoding.EncodeToString(userID[:])
+}
+
+// ParsePasskeyUserHandle reverses PasskeyUserHandle.
+func ParsePasskeyUserHandle(handle string) (primitive.ObjectID, error) {
+	raw, err := decodeBase64URL(handle)
+	if err != nil || len(raw) != len(primitive.ObjectID{}) {
+		return primitive.NilObjectID, fmt.Errorf("invalid user handle")
+	}
+
+	var id primitive.ObjectID
+	copy(id[:], raw)
+	return id, nil
+}
+
+func (w *WebAuthnManager) CreationOptions(user *entities.User, challenge string) *entities.PasskeyCreationOptions {
+	exclude := make([]entities.PasskeyCredentialDescriptor, 0, len(user.Passkeys))
+	for _, passkey := range user.Passkeys {
+		exclude = append(exclude, entities.PasskeyCredentialDescriptor{
+			Type:       "public-key",
+			ID:         passkey.ID,
+			Transports: passkey.Transports,
+		})
+	}
+
+	displayName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if displayName == "" {
+		displayName = user.Email
+	}
+
+	return &entities.PasskeyCreationOptions{
+		Challenge: challenge,
+		RP: entities.PasskeyRelyingParty{
+			ID:   w.rpID,
+			Name: w.rpName,
+		},
+		User: entities.PasskeyUserEntity{
+			ID:          PasskeyUserHandle(user.ID),
+			Name:        user.Email,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams: []entities.PasskeyCredentialParameter{
+			{Type: "public-key", Alg: coseAlgES256},
+			{Type: "public-key", Alg: coseAlgEdDSA},
+			{Type: "public-key", Alg: coseAlgRS256},
+		},
+		Timeout:            int(PasskeyCeremonyTimeout.Milliseconds()),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: entities.PasskeyAuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+func (w *WebAuthnManager) RequestOptions(challenge string) *entities.PasskeyRequestOptions {
+	return &entities.PasskeyRequestOptions{
+		Challenge:        challenge,
+		Timeout:          int(PasskeyCeremonyTimeout.Milliseconds()),
+		RPID:             w.rpID,
+		UserVerification: "required",
+	}
+}
+
+// ClientDataChallenge extracts the challenge from clientDataJSON so the caller
+// can look up the ceremony it belongs to before verifying the rest.
+func (w *WebAuthnManager) ClientDataChallenge(clientDataJSON string) (string, error) {
+	clientData, _, err := parseClientData(clientDataJSON)
+	if err != nil {
+		return "", err
+	}
+
+	return clientData.Challenge, nil
+}
+
+// VerifyRegistration checks a registration response against the challenge that
+// was issued and returns the new credential.
+func (w *WebAuthnManager) VerifyRegistration(resp *entities.PasskeyAttestationResponse, challenge string) (*entities.PasskeyCredential, error) {
+	if _, err := w.verifyClientData(resp.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := decodeBase64URL(resp.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object encoding")
+	}
+
+	decoded, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid attestation object")
+	}
+
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("attestation object has no authenticator data")
+	}
+
+	authData, err := w.parseAuthenticatorData(rawAuthData, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.flags&authDataFlagAttested == 0 || len(authData.credentialID) == 0 {
+		return nil, fmt.Errorf("authenticator data has no credential")
+	}
+
+	if _, err := parseCOSEKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &entities.PasskeyCredential{
+		ID:         base64.RawURLEncoding.EncodeToString(authData.credentialID),
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		Transports: resp.Transports,
+	}, nil
+}
+
+// VerifyAssertion checks an authentication response made with credential and
+// returns the authenticator's new signature counter.
+func (w *WebAuthnManager) VerifyAssertion(resp *entities.PasskeyAssertionResponse, challenge string, credential *entities.PasskeyCredential) (uint32, error) {
+	clientDataHash, err := w.verifyClientData(resp.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decodeBase64URL(resp.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("invalid authenticator data encoding")
+	}
+
+	authData, err := w.parseAuthenticatorData(rawAuthData, false)
+	if err != nil {
+		return 0, err
+	}
+
+	signature, err := decodeBase64URL(resp.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("invalid signature encoding")
+	}
+
+	publicKey, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash...)
+	if err := publicKey.verify(signed, signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that does not move forward suggests a cloned authenticator.
+	// Authenticators that do not keep a counter always report zero.
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, fmt.Errorf("signature counter did not increase")
+	}
+
+	return authData.signCount, nil
+}
+
+func (w *WebAuthnManager) verifyClientData(clientDataJSON, ceremonyType, challenge string) ([]byte, error) {
+	clientData, raw, err := parseClientData(clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientData.Type != ceremonyType {
+		return nil, fmt.Errorf("unexpected ceremony type %q", clientData.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+		return nil, fmt.Errorf("challenge mismatch")
+	}
+	if !w.originAllowed(clientData.Origin) {
+		return nil, fmt.Errorf("unexpected origin %q", clientData.Origin)
+	}
+
+	hash := sha256.Sum256(raw)
+	return hash[:], nil
+}
+
+func (w *WebAuthnManager) originAllowed(origin string) bool {
+	for _, allowed := range w.origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WebAuthnManager) parseAuthenticatorData(data []byte, withCredential bool) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("authenticator data too short")
+	}
+
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	expected := sha256.Sum256([]byte(w.rpID))
+	if !bytes.Equal(authData.rpIDHash, expected[:]) {
+		return nil, fmt.Errorf("relying party ID mismatch")
+	}
+	if authData.flags&authDataFlagUserPresent == 0 {
+		return nil, fmt.Errorf("user presence was not confirmed")
+	}
+	if authData.flags&authDataFlagUserVerified == 0 {
+		return nil, fmt.Errorf("user verification was not performed")
+	}
+
+	if !withCredential || authData.flags&authDataFlagAttested == 0 {
+		return authData, nil
+	}
+
+	// Attested credential data: 16 byte AAGUID, 2 byte length, credential ID, COSE key
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("attested credential data too short")
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, fmt.Errorf("credential ID longer than data")
+	}
+	authData.credentialID = append([]byte(nil), rest[:idLength]...)
+	rest = rest[idLength:]
+
+	_, keyLength, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	authData.publicKey = append([]byte(nil), rest[:keyLength]...)
+
+	return authData, nil
+}
+
+func parseClientData(clientDataJSON string) (*collectedClientData, []byte, error) {
+	raw, err := decodeBase64URL(clientDataJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid client data encoding")
+	}
+
+	var clientData collectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, nil, fmt.Errorf("invalid client data: %w", err)
+	}
+
+	return &clientData, raw, nil
+}
+
+type cosePublicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+func parseCOSEKey(data []byte) (*cosePublicKey, error) {
+	decoded, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+
+	fields, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid credential public key")
+	}
+
+	alg, _ := fields[int64(3)].(int64)
+	switch alg {
+	case coseAlgES256:
+		x, _ := fields[int64(-2)].([]byte)
+		y, _ := fields[int64(-3)].([]byte)
+		if crv, _ := fields[int64(-1)].(int64); crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("unsupported EC2 key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("EC2 key is not on the curve")
+		}
+		return &cosePublicKey{alg: alg, key: key}, nil
+	case coseAlgEdDSA:
+		x, _ := fields[int64(-2)].([]byte)
+		if crv, _ := fields[int64(-1)].(int64); crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported OKP key")
+		}
+		return &cosePublicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case coseAlgRS256:
+		n, _ := fields[int64(-1)].([]byte)
+		e, _ := fields[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("unsupported RSA key")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return &cosePublicKey{alg: alg, key: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credential algorithm %d", alg)
+	}
+}
+
+func (k *cosePublicKey) verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+
+	var ok bool
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !ok {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, which is what
+// browsers and WebAuthn libraries produce.
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/infrastructure/auth/webauthntest"
+	"devfolio-backend/infrastructure/config"
+)
+
+const (
+	testRPID   = "devfolio.test"
+	testOrigin = "https://devfolio.test"
+)
+
+func newTestWebAuthn(t *testing.T) (*WebAuthnManager, *webauthntest.Authenticator) {
+	t.Helper()
+
+	manager := NewWebAuthnManager(&config.Config{
+		WebAuthn: config.WebAuthnConfig{RPID: testRPID, RPName: "DevFolio", Origins: testOrigin},
+	})
+	authenticator, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager, authenticator
+}
+
+// registerTestPasskey runs a successful registration and returns the stored credential.
+func registerTestPasskey(t *testing.T, manager *WebAuthnManager, authenticator *webauthntest.Authenticator) *entities.PasskeyCredential {
+	t.Helper()
+
+	credential, err := manager.VerifyRegistration(authenticator.Register("register-challenge"), "register-challenge")
+	if err != nil {
+		t.Fatalf("VerifyRegistration() error = %v", err)
+	}
+	return credential
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	manager, authenticator := newTestWebAuthn(t)
+
+	credential := registerTestPasskey(t, manager, authenticator)
+	if credential.ID != authenticator.ID() {
+		t.Errorf("credential ID = %q, want %q", credential.ID, authenticator.ID())
+	}
+	if string(credential.PublicKey) != string(authenticator.PublicKey()) {
+		t.Error("stored public key does not match the authenticator's")
+	}
+	if len(credential.Transports) != 1 || credential.Transports[0] != "internal" {
+		t.Errorf("transports = %v, want [internal]", credential.Transports)
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(a *webauthntest.Authenticator)
+		verify  string
+		wantErr string
+	}{
+		{
+			name:    "wrong challenge",
+			verify:  "other-challenge",
+			wantErr: "challenge mismatch",
+		},
+		{
+			name:    "wrong origin",
+			modify:  func(a *webauthntest.Authenticator) { a.Origin = "https://evil.test" },
+			wantErr: "unexpected origin",
+		},
+		{
+			name:    "wrong RP ID hash",
+			modify:  func(a *webauthntest.Authenticator) { a.RPID = "evil.test" },
+			wantErr: "relying party ID mismatch",
+		},
+		{
+			name:    "user not verified",
+			modify:  func(a *webauthntest.Authenticator) { a.Flags = 0x01 },
+			wantErr: "user verification",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, authenticator := newTestWebAuthn(t)
+			if tt.modify != nil {
+				tt.modify(authenticator)
+			}
+			verify := tt.verify
+			if verify == "" {
+				verify = "challenge"
+			}
+
+			_, err := manager.VerifyRegistration(authenticator.Register("challenge"), verify)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("VerifyRegistration() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRejectsAssertion(t *testing.T) {
+	manager, authenticator := newTestWebAuthn(t)
+
+	// clientDataJSON from a login must not complete a registration
+	assertion := authenticator.Login("challenge", "")
+	response := authenticator.Register("challenge")
+	response.ClientDataJSON = assertion.ClientDataJSON
+
+	if _, err := manager.VerifyRegistration(response, "challenge"); err == nil {
+		t.Fatal("VerifyRegistration() accepted a webauthn.get client data")
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	manager, authenticator := newTestWebAuthn(t)
+	credential := registerTestPasskey(t, manager, authenticator)
+
+	for i := 0; i < 2; i++ {
+		signCount, err := manager.VerifyAssertion(authenticator.Login("login-challenge", ""), "login-challenge", credential)
+		if err != nil {
+			t.Fatalf("VerifyAssertion() error = %v", err)
+		}
+		if signCount != authenticator.SignCount {
+			t.Errorf("sign count = %d, want %d", signCount, authenticator.SignCount)
+		}
+		credential.SignCount = signCount
+	}
+}
+
+func TestVerifyAssertionWithoutCounter(t *testing.T) {
+	manager, authenticator := newTestWebAuthn(t)
+	credential := registerTestPasskey(t, manager, authenticator)
+
+	// Authenticators without a counter always report zero
+	authenticator.SignCount = ^uint32(0)
+	if _, err := manager.VerifyAssertion(authenticator.Login("challenge", ""), "challenge", credential); err != nil {
+		t.Fatalf("VerifyAssertion() error = %v", err)
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(a *webauthntest.Authenticator, credential *entities.PasskeyCredential)
+		tamper  func(resp *entities.PasskeyAssertionResponse)
+		verify  string
+		wantErr string
+	}{
+		{
+			name:    "wrong challenge",
+			verify:  "other-challenge",
+			wantErr: "challenge mismatch",
+		},
+		{
+			name: "wrong origin",
+			modify: func(a *webauthntest.Authenticator, _ *entities.PasskeyCredential) {
+				a.Origin = "https://evil.test"
+			},
+			wantErr: "unexpected origin",
+		},
+		{
+			name: "wrong RP ID hash",
+			modify: func(a *webauthntest.Authenticator, _ *entities.PasskeyCredential) {
+				a.RPID = "evil.test"
+			},
+			wantErr: "relying party ID mismatch",
+		},
+		{
+			name: "sign count regression",
+			modify: func(a *webauthntest.Authenticator, credential *entities.PasskeyCredential) {
+				credential.SignCount = 10
+				a.SignCount = 4
+			},
+			wantErr: "signature counter did not increase",
+		},
+		{
+			name: "replayed sign count",
+			modify: func(a *webauthntest.Authenticator, credential *entities.PasskeyCredential) {
+				credential.SignCount = 10
+				a.SignCount = 9
+			},
+			wantErr: "signature counter did not increase",
+		},
+		{
+			name: "bad signature",
+			tamper: func(resp *entities.PasskeyAssertionResponse) {
+				signature, _ := decodeBase64URL(resp.Signature)
+				signature[len(signature)-1] ^= 0xff
+				resp.Signature = base64.RawURLEncoding.EncodeToString(signature)
+			},
+			wantErr: "invalid signature",
+		},
+		{
+			name: "signature over other data",
+			tamper: func(resp *entities.PasskeyAssertionResponse) {
+				authData, _ := decodeBase64URL(resp.AuthenticatorData)
+				authData[33]++
+				resp.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+			},
+			wantErr: "invalid signature",
+		},
+		{
+			name: "signed by another key",
+			modify: func(_ *webauthntest.Authenticator, credential *entities.PasskeyCredential) {
+				other, err := webauthntest.New(testRPID, testOrigin)
+				if err != nil {
+					panic(err)
+				}
+				credential.PublicKey = other.PublicKey()
+			},
+			wantErr: "invalid signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, authenticator := newTestWebAuthn(t)
+			credential := registerTestPasskey(t, manager, authenticator)
+			if tt.modify != nil {
+				tt.modify(authenticator, credential)
+			}
+			verify := tt.verify
+			if verify == "" {
+				verify = "challenge"
+			}
+
+			resp := authenticator.Login("challenge", "")
+			if tt.tamper != nil {
+				tt.tamper(resp)
+			}
+
+			_, err := manager.VerifyAssertion(resp, verify, credential)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("VerifyAssertion() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasskeyUserHandleRoundTrip(t *testing.T) {
+	user := &entities.User{}
+	user.ID[0] = 0x42
+
+	id, err := ParsePasskeyUserHandle(PasskeyUserHandle(user.ID))
+	if err != nil || id != user.ID {
+		t.Fatalf("ParsePasskeyUserHandle() = %v, %v, want %v", id, err, user.ID)
+	}
+	if _, err := ParsePasskeyUserHandle("not-a-handle"); err == nil {
+		t.Fatal("ParsePasskeyUserHandle() accepted an invalid handle")
+	}
+}
@@ -0,0 +1,182 @@
+// Package webauthntest provides a software authenticator for testing WebAuthn
+// ceremonies without a browser or security key.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"devfolio-backend/domain/entities"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// Authenticator holds one ES256 passkey. Its exported fields can be changed
+// between calls to produce responses a server must reject.
+type Authenticator struct {
+	RPID         string
+	Origin       string
+	CredentialID []byte
+	SignCount    uint32
+	// Flags defaults to user present and user verified
+	Flags byte
+
+	key *ecdsa.PrivateKey
+}
+
+func New(rpID, origin string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate passkey: %w", err)
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, fmt.Errorf("failed to generate credential ID: %w", err)
+	}
+
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: credentialID,
+		Flags:        flagUserPresent | flagUserVerified,
+		key:          key,
+	}, nil
+}
+
+// ID is the base64url credential ID browsers report.
+func (a *Authenticator) ID() string {
+	return base64.RawURLEncoding.EncodeToString(a.CredentialID)
+}
+
+// Register answers navigator.credentials.create with a "none" attestation.
+func (a *Authenticator) Register(challenge string) *entities.PasskeyAttestationResponse {
+	clientData := a.clientData("webauthn.create", challenge)
+
+	var credentialData []byte
+	credentialData = append(credentialData, make([]byte, 16)...) // AAGUID
+	credentialData = binary.BigEndian.AppendUint16(credentialData, uint16(len(a.CredentialID)))
+	credentialData = append(credentialData, a.CredentialID...)
+	credentialData = append(credentialData, a.PublicKey()...)
+
+	authData := append(a.authData(a.Flags|flagAttested), credentialData...)
+	attestation := encodeCBOR(cborMap{
+		{"fmt", "none"},
+		{"attStmt", cborMap{}},
+		{"authData", authData},
+	})
+
+	return &entities.PasskeyAttestationResponse{
+		ClientDataJSON:    encode(clientData),
+		AttestationObject: encode(attestation),
+		Transports:        []string{"internal"},
+	}
+}
+
+// Login answers navigator.credentials.get, bumping the signature counter
+// first as real authenticators do.
+func (a *Authenticator) Login(challenge, userHandle string) *entities.PasskeyAssertionResponse {
+	a.SignCount++
+	clientData := a.clientData("webauthn.get", challenge)
+	authData := a.authData(a.Flags)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(fmt.Sprintf("webauthntest: failed to sign assertion: %v", err))
+	}
+
+	return &entities.PasskeyAssertionResponse{
+		ClientDataJSON:    encode(clientData),
+		AuthenticatorData: encode(authData),
+		Signature:         encode(signature),
+		UserHandle:        userHandle,
+	}
+}
+
+// PublicKey returns the credential public key as a COSE EC2 key.
+func (a *Authenticator) PublicKey() []byte {
+	return encodeCBOR(cborMap{
+		{int64(1), int64(2)},  // kty: EC2
+		{int64(3), int64(-7)}, // alg: ES256
+		{int64(-1), int64(1)}, // crv: P-256
+		{int64(-2), a.key.X.FillBytes(make([]byte, 32))},
+		{int64(-3), a.key.Y.FillBytes(make([]byte, 32))},
+	})
+}
+
+func (a *Authenticator) clientData(ceremonyType, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremonyType,
+		"challenge": challenge,
+		"origin":    a.Origin,
+	})
+	return data
+}
+
+func (a *Authenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// cborMap keeps its entries in order so encodings are deterministic.
+type cborMap []cborEntry
+
+type cborEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+// encodeCBOR encodes the few CBOR types WebAuthn responses contain.
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case cborMap:
+		out := cborHead(5, uint64(len(v)))
+		for _, entry := range v {
+			out = append(out, encodeCBOR(entry.key)...)
+			out = append(out, encodeCBOR(entry.value)...)
+		}
+		return out
+	default:
+		panic(fmt.Sprintf("webauthntest: cannot encode %T", value))
+	}
+}
+
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+}
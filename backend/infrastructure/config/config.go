@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -16,6 +17,19 @@ type Config struct {
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	Cookie   CookieConfig   `mapstructure:"cookie"`
 	Google   GoogleConfig   `mapstructure:"google"`
+	Auth     AuthConfig     `mapstructure:"auth"`
+	Mail     MailConfig     `mapstructure:"mail"`
+	WebAuthn WebAuthnConfig `mapstructure:"webauthn"`
+	Export   ExportConfig   `mapstructure:"export"`
+
+	Registration RegistrationConfig `mapstructure:"registration"`
+
+	LoginThrottle  LoginThrottleConfig  `mapstructure:"login_throttle"`
+	PasswordPolicy PasswordPolicyConfig `mapstructure:"password_policy"`
+	PasswordHash   PasswordHashConfig   `mapstructure:"password_hash"`
+
+	// OAuth lists external login providers keyed by route name
+	OAuth map[string]OAuthProviderConfig `mapstructure:"oauth"`
 }
 
 type DatabaseConfig struct {
@@ -26,6 +40,9 @@ type DatabaseConfig struct {
 type ServerConfig struct {
 	Port    string `mapstructure:"port"`
 	GinMode string `mapstructure:"gin_mode"`
+	// TrustedProxies is a comma separated list of proxy IPs or CIDRs whose
+	// X-Forwarded-For header is believed. When empty no proxy is trusted.
+	TrustedProxies string `mapstructure:"trusted_proxies"`
 }
 
 type AIConfig struct {
@@ -38,9 +55,11 @@ type CORSConfig struct {
 }
 
 type JWTConfig struct {
-	Secret        string `mapstructure:"secret"`
-	AccessExpiry  string `mapstructure:"access_expiry"`
-	RefreshExpiry string `mapstructure:"refresh_expiry"`
+	Secret               string `mapstructure:"secret"`
+	SigningKeyFile       string `mapstructure:"signing_key_file"`
+	VerificationKeyFiles string `mapstructure:"verification_key_files"`
+	AccessExpiry         string `mapstructure:"access_expiry"`
+	RefreshExpiry        string `mapstructure:"refresh_expiry"`
 }
 
 type CookieConfig struct {
@@ -55,6 +74,129 @@ type GoogleConfig struct {
 	RedirectURL  string `mapstructure:"redirect_url"`
 }
 
+// OAuthProviderConfig configures one external login provider. A provider is
+// enabled once it has a client ID and secret.
+type OAuthProviderConfig struct {
+	// Type is google, github, gitlab, microsoft or oidc; it defaults to the provider name
+	Type         string `mapstructure:"type"`
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	RedirectURL  string `mapstructure:"redirect_url"`
+	// Issuer is used for OIDC discovery, e.g. a self-hosted GitLab or a Microsoft tenant
+	Issuer string `mapstructure:"issuer"`
+	// Scopes is space separated and replaces the provider's defaults
+	Scopes string `mapstructure:"scopes"`
+	// TrustEmail treats the email as verified for issuers that never send email_verified
+	TrustEmail bool `mapstructure:"trust_email"`
+	// Endpoint overrides for providers without discovery, such as GitHub Enterprise
+	AuthURL     string `mapstructure:"auth_url"`
+	TokenURL    string `mapstructure:"token_url"`
+	UserInfoURL string `mapstructure:"userinfo_url"`
+}
+
+type AuthConfig struct {
+	EmailVerificationExpiry       string `mapstructure:"email_verification_expiry"`
+	RequireVerifiedEmailToPublish bool   `mapstructure:"require_verified_email_to_publish"`
+	PasswordResetExpiry           string `mapstructure:"password_reset_expiry"`
+	TOTPIssuer                    string `mapstructure:"totp_issuer"`
+	MFATokenExpiry                string `mapstructure:"mfa_token_expiry"`
+	MagicLinkExpiry               string `mapstructure:"magic_link_expiry"`
+	// MagicLinkURL is the API's magic link endpoint, which emailed links point to
+	MagicLinkURL string `mapstructure:"magic_link_url"`
+	// AdminEmails is a comma separated list of accounts promoted to admin at startup
+	AdminEmails string `mapstructure:"admin_emails"`
+	// NewDeviceAlerts emails users about sign-ins from an unknown device or network
+	NewDeviceAlerts bool `mapstructure:"new_device_alerts"`
+	// SecureAccountExpiry is the lifetime of the "this wasn't me" link in those emails
+	SecureAccountExpiry string `mapstructure:"secure_account_expiry"`
+	// SecureAccountURL is the API's endpoint the "this wasn't me" link points to
+	SecureAccountURL string `mapstructure:"secure_account_url"`
+	// ImpersonationExpiry is the lifetime of the access token an admin gets to act as a user
+	ImpersonationExpiry string `mapstructure:"impersonation_expiry"`
+	// AccountDeletionGracePeriod is how long a deleted account can be restored by signing in
+	AccountDeletionGracePeriod string `mapstructure:"account_deletion_grace_period"`
+	AccountPurgeInterval       string `mapstructure:"account_purge_interval"`
+}
+
+type MailConfig struct {
+	Driver       string `mapstructure:"driver"`
+	From         string `mapstructure:"from"`
+	OutboxDir    string `mapstructure:"outbox_dir"`
+	SMTPHost     string `mapstructure:"smtp_host"`
+	SMTPPort     string `mapstructure:"smtp_port"`
+	SMTPUsername string `mapstructure:"smtp_username"`
+	SMTPPassword string `mapstructure:"smtp_password"`
+}
+
+type WebAuthnConfig struct {
+	RPID   string `mapstructure:"rp_id"`
+	RPName string `mapstructure:"rp_name"`
+	// Origins is a comma separated list; it defaults to cors.frontend_url.
+	Origins string `mapstructure:"origins"`
+}
+
+// RegistrationConfig controls who can create an account, by password, login
+// provider or magic link.
+type RegistrationConfig struct {
+	// Mode is open, invite (an admin-issued invite code is required) or domain
+	Mode string `mapstructure:"mode"`
+	// AllowedDomains is a comma separated list of email domains allowed in domain mode
+	AllowedDomains string `mapstructure:"allowed_domains"`
+	// DisposableDomainsFile lists throwaway email domains, one per line, that
+	// cannot be used to sign up in any mode
+	DisposableDomainsFile string `mapstructure:"disposable_domains_file"`
+}
+
+// ExportConfig controls "download my data" archives.
+type ExportConfig struct {
+	Dir string `mapstructure:"dir"`
+	// Expiry is how long a finished archive is kept before it is deleted
+	Expiry string `mapstructure:"expiry"`
+	// LinkExpiry is how long each download link works
+	LinkExpiry      string `mapstructure:"link_expiry"`
+	CleanupInterval string `mapstructure:"cleanup_interval"`
+}
+
+type LoginThrottleConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Storage is "mongo" or "memory". Mongo shares the counters between
+	// instances and is used whenever the database is available.
+	Storage string `mapstructure:"storage"`
+	// Failures allowed per email address or IP before backoff starts
+	FreeAttempts   int    `mapstructure:"free_attempts"`
+	IPFreeAttempts int    `mapstructure:"ip_free_attempts"`
+	BaseDelay      string `mapstructure:"base_delay"`
+	MaxDelay       string `mapstructure:"max_delay"`
+	// Failures after which the email address or IP is locked out
+	LockoutThreshold   int    `mapstructure:"lockout_threshold"`
+	IPLockoutThreshold int    `mapstructure:"ip_lockout_threshold"`
+	LockoutDuration    string `mapstructure:"lockout_duration"`
+	// Window is how long failures are remembered after the last one
+	Window string `mapstructure:"window"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength int `mapstructure:"min_length"`
+	// MaxLength is in bytes
+	MaxLength          int  `mapstructure:"max_length"`
+	RequireUppercase   bool `mapstructure:"require_uppercase"`
+	RequireLowercase   bool `mapstructure:"require_lowercase"`
+	RequireDigit       bool `mapstructure:"require_digit"`
+	RequireSymbol      bool `mapstructure:"require_symbol"`
+	RejectPersonalInfo bool `mapstructure:"reject_personal_info"`
+	// BreachedPasswordFile is an offline HIBP SHA-1 list: a directory of
+	// range files or one file ordered by hash. Empty disables the check.
+	BreachedPasswordFile string `mapstructure:"breached_password_file"`
+}
+
+// PasswordHashConfig tunes Argon2id. Existing hashes made with other
+// parameters are upgraded on the user's next successful login.
+type PasswordHashConfig struct {
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"` // KiB
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+}
+
 func LoadConfig() (*Config, error) {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
@@ -95,9 +237,12 @@ func setDefaults() {
 	viper.SetDefault("database.name", "devfolio")
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.gin_mode", "release")
+	viper.SetDefault("server.trusted_proxies", "")
 	viper.SetDefault("ai.openai_model", "gpt-4o-mini")
 	viper.SetDefault("cors.frontend_url", "http://localhost:3000")
 	viper.SetDefault("jwt.secret", "devfolio-default-secret-key-change-in-production")
+	viper.SetDefault("jwt.signing_key_file", "")
+	viper.SetDefault("jwt.verification_key_files", "")
 	viper.SetDefault("jwt.access_expiry", "15m")
 	viper.SetDefault("jwt.refresh_expiry", "168h")
 	viper.SetDefault("cookie.domain", "")
@@ -106,6 +251,58 @@ func setDefaults() {
 	viper.SetDefault("google.client_id", "")
 	viper.SetDefault("google.client_secret", "")
 	viper.SetDefault("google.redirect_url", "http://localhost:8080/api/v1/auth/google/callback")
+	viper.SetDefault("auth.email_verification_expiry", "24h")
+	viper.SetDefault("auth.require_verified_email_to_publish", false)
+	viper.SetDefault("auth.password_reset_expiry", "1h")
+	viper.SetDefault("auth.totp_issuer", "DevFolio")
+	viper.SetDefault("auth.mfa_token_expiry", "5m")
+	viper.SetDefault("auth.magic_link_expiry", "15m")
+	viper.SetDefault("auth.magic_link_url", "http://localhost:8080/api/v1/auth/magic-link/verify")
+	viper.SetDefault("auth.admin_emails", "")
+	viper.SetDefault("auth.impersonation_expiry", "15m")
+	viper.SetDefault("auth.new_device_alerts", true)
+	viper.SetDefault("auth.secure_account_expiry", "168h")
+	viper.SetDefault("auth.secure_account_url", "http://localhost:8080/api/v1/auth/secure-account")
+	viper.SetDefault("auth.account_deletion_grace_period", "720h")
+	viper.SetDefault("auth.account_purge_interval", "1h")
+	viper.SetDefault("webauthn.rp_id", "localhost")
+	viper.SetDefault("webauthn.rp_name", "DevFolio")
+	viper.SetDefault("webauthn.origins", "")
+	viper.SetDefault("export.dir", "./exports")
+	viper.SetDefault("export.expiry", "48h")
+	viper.SetDefault("export.link_expiry", "15m")
+	viper.SetDefault("export.cleanup_interval", "1h")
+	viper.SetDefault("registration.mode", "open")
+	viper.SetDefault("registration.allowed_domains", "")
+	viper.SetDefault("registration.disposable_domains_file", "")
+	viper.SetDefault("login_throttle.enabled", true)
+	viper.SetDefault("login_throttle.storage", "mongo")
+	viper.SetDefault("login_throttle.free_attempts", 3)
+	viper.SetDefault("login_throttle.ip_free_attempts", 20)
+	viper.SetDefault("login_throttle.base_delay", "1s")
+	viper.SetDefault("login_throttle.max_delay", "15m")
+	viper.SetDefault("login_throttle.lockout_threshold", 10)
+	viper.SetDefault("login_throttle.ip_lockout_threshold", 100)
+	viper.SetDefault("login_throttle.lockout_duration", "30m")
+	viper.SetDefault("login_throttle.window", "1h")
+	viper.SetDefault("password_policy.min_length", 8)
+	viper.SetDefault("password_policy.max_length", 128)
+	viper.SetDefault("password_policy.require_uppercase", false)
+	viper.SetDefault("password_policy.require_lowercase", false)
+	viper.SetDefault("password_policy.require_digit", false)
+	viper.SetDefault("password_policy.require_symbol", false)
+	viper.SetDefault("password_policy.reject_personal_info", true)
+	viper.SetDefault("password_policy.breached_password_file", "")
+	viper.SetDefault("password_hash.argon2_memory", 64*1024)
+	viper.SetDefault("password_hash.argon2_iterations", 3)
+	viper.SetDefault("password_hash.argon2_parallelism", 2)
+	viper.SetDefault("mail.driver", "file")
+	viper.SetDefault("mail.from", "DevFolio <no-reply@devfolio.local>")
+	viper.SetDefault("mail.outbox_dir", "./outbox")
+	viper.SetDefault("mail.smtp_host", "")
+	viper.SetDefault("mail.smtp_port", "587")
+	viper.SetDefault("mail.smtp_username", "")
+	viper.SetDefault("mail.smtp_password", "")
 }
 
 func overrideWithEnvVars() {
@@ -121,6 +318,9 @@ func overrideWithEnvVars() {
 	if mode := os.Getenv("GIN_MODE"); mode != "" {
 		viper.Set("server.gin_mode", mode)
 	}
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		viper.Set("server.trusted_proxies", proxies)
+	}
 	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
 		viper.Set("ai.openai_api_key", key)
 	}
@@ -133,6 +333,12 @@ func overrideWithEnvVars() {
 	if secret := os.Getenv("JWT_SECRET"); secret != "" {
 		viper.Set("jwt.secret", secret)
 	}
+	if file := os.Getenv("JWT_SIGNING_KEY_FILE"); file != "" {
+		viper.Set("jwt.signing_key_file", file)
+	}
+	if files := os.Getenv("JWT_VERIFICATION_KEY_FILES"); files != "" {
+		viper.Set("jwt.verification_key_files", files)
+	}
 	if expiry := os.Getenv("JWT_ACCESS_EXPIRY"); expiry != "" {
 		viper.Set("jwt.access_expiry", expiry)
 	}
@@ -157,4 +363,169 @@ func overrideWithEnvVars() {
 	if redirectURL := os.Getenv("GOOGLE_REDIRECT_URL"); redirectURL != "" {
 		viper.Set("google.redirect_url", redirectURL)
 	}
+	// GITHUB_CLIENT_ID, GITLAB_ISSUER, MICROSOFT_REDIRECT_URL, OIDC_CLIENT_SECRET, ...
+	for _, provider := range []string{"github", "gitlab", "microsoft", "oidc"} {
+		prefix := strings.ToUpper(provider) + "_"
+		for _, field := range []string{"client_id", "client_secret", "redirect_url", "issuer"} {
+			if value := os.Getenv(prefix + strings.ToUpper(field)); value != "" {
+				viper.Set("oauth."+provider+"."+field, value)
+			}
+		}
+	}
+	if expiry := os.Getenv("EMAIL_VERIFICATION_EXPIRY"); expiry != "" {
+		viper.Set("auth.email_verification_expiry", expiry)
+	}
+	if require := os.Getenv("REQUIRE_VERIFIED_EMAIL_TO_PUBLISH"); require != "" {
+		viper.Set("auth.require_verified_email_to_publish", require == "true")
+	}
+	if expiry := os.Getenv("PASSWORD_RESET_EXPIRY"); expiry != "" {
+		viper.Set("auth.password_reset_expiry", expiry)
+	}
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		viper.Set("auth.totp_issuer", issuer)
+	}
+	if expiry := os.Getenv("MFA_TOKEN_EXPIRY"); expiry != "" {
+		viper.Set("auth.mfa_token_expiry", expiry)
+	}
+	if expiry := os.Getenv("MAGIC_LINK_EXPIRY"); expiry != "" {
+		viper.Set("auth.magic_link_expiry", expiry)
+	}
+	if magicLinkURL := os.Getenv("MAGIC_LINK_URL"); magicLinkURL != "" {
+		viper.Set("auth.magic_link_url", magicLinkURL)
+	}
+	if expiry := os.Getenv("IMPERSONATION_EXPIRY"); expiry != "" {
+		viper.Set("auth.impersonation_expiry", expiry)
+	}
+	if alerts := os.Getenv("NEW_DEVICE_ALERTS"); alerts != "" {
+		viper.Set("auth.new_device_alerts", alerts == "true")
+	}
+	if expiry := os.Getenv("SECURE_ACCOUNT_EXPIRY"); expiry != "" {
+		viper.Set("auth.secure_account_expiry", expiry)
+	}
+	if secureAccountURL := os.Getenv("SECURE_ACCOUNT_URL"); secureAccountURL != "" {
+		viper.Set("auth.secure_account_url", secureAccountURL)
+	}
+	if emails := os.Getenv("ADMIN_EMAILS"); emails != "" {
+		viper.Set("auth.admin_emails", emails)
+	}
+	if grace := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); grace != "" {
+		viper.Set("auth.account_deletion_grace_period", grace)
+	}
+	if interval := os.Getenv("ACCOUNT_PURGE_INTERVAL"); interval != "" {
+		viper.Set("auth.account_purge_interval", interval)
+	}
+	if rpID := os.Getenv("WEBAUTHN_RP_ID"); rpID != "" {
+		viper.Set("webauthn.rp_id", rpID)
+	}
+	if rpName := os.Getenv("WEBAUTHN_RP_NAME"); rpName != "" {
+		viper.Set("webauthn.rp_name", rpName)
+	}
+	if origins := os.Getenv("WEBAUTHN_ORIGINS"); origins != "" {
+		viper.Set("webauthn.origins", origins)
+	}
+	if dir := os.Getenv("DATA_EXPORT_DIR"); dir != "" {
+		viper.Set("export.dir", dir)
+	}
+	if expiry := os.Getenv("DATA_EXPORT_EXPIRY"); expiry != "" {
+		viper.Set("export.expiry", expiry)
+	}
+	if expiry := os.Getenv("DATA_EXPORT_LINK_EXPIRY"); expiry != "" {
+		viper.Set("export.link_expiry", expiry)
+	}
+	if interval := os.Getenv("DATA_EXPORT_CLEANUP_INTERVAL"); interval != "" {
+		viper.Set("export.cleanup_interval", interval)
+	}
+	if mode := os.Getenv("REGISTRATION_MODE"); mode != "" {
+		viper.Set("registration.mode", mode)
+	}
+	if domains := os.Getenv("REGISTRATION_ALLOWED_DOMAINS"); domains != "" {
+		viper.Set("registration.allowed_domains", domains)
+	}
+	if file := os.Getenv("DISPOSABLE_DOMAINS_FILE"); file != "" {
+		viper.Set("registration.disposable_domains_file", file)
+	}
+	if enabled := os.Getenv("LOGIN_THROTTLE_ENABLED"); enabled != "" {
+		viper.Set("login_throttle.enabled", enabled == "true")
+	}
+	if storage := os.Getenv("LOGIN_THROTTLE_STORAGE"); storage != "" {
+		viper.Set("login_throttle.storage", storage)
+	}
+	if attempts := os.Getenv("LOGIN_FREE_ATTEMPTS"); attempts != "" {
+		viper.Set("login_throttle.free_attempts", attempts)
+	}
+	if attempts := os.Getenv("LOGIN_IP_FREE_ATTEMPTS"); attempts != "" {
+		viper.Set("login_throttle.ip_free_attempts", attempts)
+	}
+	if delay := os.Getenv("LOGIN_BASE_DELAY"); delay != "" {
+		viper.Set("login_throttle.base_delay", delay)
+	}
+	if delay := os.Getenv("LOGIN_MAX_DELAY"); delay != "" {
+		viper.Set("login_throttle.max_delay", delay)
+	}
+	if threshold := os.Getenv("LOGIN_LOCKOUT_THRESHOLD"); threshold != "" {
+		viper.Set("login_throttle.lockout_threshold", threshold)
+	}
+	if threshold := os.Getenv("LOGIN_IP_LOCKOUT_THRESHOLD"); threshold != "" {
+		viper.Set("login_throttle.ip_lockout_threshold", threshold)
+	}
+	if duration := os.Getenv("LOGIN_LOCKOUT_DURATION"); duration != "" {
+		viper.Set("login_throttle.lockout_duration", duration)
+	}
+	if window := os.Getenv("LOGIN_THROTTLE_WINDOW"); window != "" {
+		viper.Set("login_throttle.window", window)
+	}
+	if length := os.Getenv("PASSWORD_MIN_LENGTH"); length != "" {
+		viper.Set("password_policy.min_length", length)
+	}
+	if length := os.Getenv("PASSWORD_MAX_LENGTH"); length != "" {
+		viper.Set("password_policy.max_length", length)
+	}
+	if require := os.Getenv("PASSWORD_REQUIRE_UPPERCASE"); require != "" {
+		viper.Set("password_policy.require_uppercase", require == "true")
+	}
+	if require := os.Getenv("PASSWORD_REQUIRE_LOWERCASE"); require != "" {
+		viper.Set("password_policy.require_lowercase", require == "true")
+	}
+	if require := os.Getenv("PASSWORD_REQUIRE_DIGIT"); require != "" {
+		viper.Set("password_policy.require_digit", require == "true")
+	}
+	if require := os.Getenv("PASSWORD_REQUIRE_SYMBOL"); require != "" {
+		viper.Set("password_policy.require_symbol", require == "true")
+	}
+	if reject := os.Getenv("PASSWORD_REJECT_PERSONAL_INFO"); reject != "" {
+		viper.Set("password_policy.reject_personal_info", reject == "true")
+	}
+	if file := os.Getenv("BREACHED_PASSWORD_FILE"); file != "" {
+		viper.Set("password_policy.breached_password_file", file)
+	}
+	if memory := os.Getenv("ARGON2_MEMORY"); memory != "" {
+		viper.Set("password_hash.argon2_memory", memory)
+	}
+	if iterations := os.Getenv("ARGON2_ITERATIONS"); iterations != "" {
+		viper.Set("password_hash.argon2_iterations", iterations)
+	}
+	if parallelism := os.Getenv("ARGON2_PARALLELISM"); parallelism != "" {
+		viper.Set("password_hash.argon2_parallelism", parallelism)
+	}
+	if driver := os.Getenv("MAIL_DRIVER"); driver != "" {
+		viper.Set("mail.driver", driver)
+	}
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		viper.Set("mail.from", from)
+	}
+	if dir := os.Getenv("MAIL_OUTBOX_DIR"); dir != "" {
+		viper.Set("mail.outbox_dir", dir)
+	}
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		viper.Set("mail.smtp_host", host)
+	}
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		viper.Set("mail.smtp_port", port)
+	}
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		viper.Set("mail.smtp_username", username)
+	}
+	if password := os.Getenv("SMTP_PASSWORD"); password != "" {
+		viper.Set("mail.smtp_password", password)
+	}
 }
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+
+	"devfolio-backend/infrastructure/config"
+)
+
+type SMTPMailer struct {
+	from     string
+	host     string
+	port     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(cfg *config.Config) *SMTPMailer {
+	return &SMTPMailer{
+		from:     cfg.Mail.From,
+		host:     cfg.Mail.SMTPHost,
+		port:     cfg.Mail.SMTPPort,
+		username: cfg.Mail.SMTPUsername,
+		password: cfg.Mail.SMTPPassword,
+	}
+}
+
+func (m *SMTPMailer) Send(_ context.Context, msg *Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	addr := net.JoinHostPort(m.host, m.port)
+	if err := smtp.SendMail(addr, auth, m.from, []string{msg.To}, buildMessage(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
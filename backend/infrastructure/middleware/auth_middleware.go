@@ -1,15 +1,27 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
 	"devfolio-backend/infrastructure/auth"
-	
+
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
+// TokenAuthenticator resolves a personal access token to the token and its owner.
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*entities.PersonalAccessToken, *entities.User, error)
+}
+
+// AuthMiddleware accepts access tokens, and personal access tokens when tokens
+// is not nil. Every route behind it that accepts personal access tokens must
+// also use RequireScope.
+func AuthMiddleware(jwtManager *auth.JWTManager, sessionRepo repositories.SessionRepository, tokens TokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -33,24 +45,59 @@ func AuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(token, entities.PersonalAccessTokenPrefix) {
+			if tokens == nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "personal access tokens are not accepted here"})
+				c.Abort()
+				return
+			}
+
+			pat, user, err := tokens.Authenticate(c.Request.Context(), token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", user.ID.Hex())
+			c.Set("user_email", user.Email)
+			c.Set("user_role", user.EffectiveRole())
+			c.Set("personal_access_token", pat)
+
+			c.Next()
+			return
+		}
+
 		// Validate token
-		claims, err := jwtManager.ValidateToken(token)
+		claims, err := jwtManager.ValidateAccessToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
 			return
 		}
 
+		// Access tokens die with the session they were issued for
+		if !sessionActive(c, sessionRepo, claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set user information in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		if claims.Actor != nil {
+			c.Set("impersonator_id", claims.Actor.UserID)
+		}
 
 		c.Next()
 	}
 }
 
 // OptionalAuthMiddleware allows both authenticated and unauthenticated requests
-func OptionalAuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
+func OptionalAuthMiddleware(jwtManager *auth.JWTManager, sessionRepo repositories.SessionRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -61,13 +108,83 @@ func OptionalAuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 		if strings.HasPrefix(authHeader, "Bearer ") {
 			token := strings.TrimPrefix(authHeader, "Bearer ")
 			if token != "" {
-				if claims, err := jwtManager.ValidateToken(token); err == nil {
+				if claims, err := jwtManager.ValidateAccessToken(token); err == nil && sessionActive(c, sessionRepo, claims.SessionID) {
 					c.Set("user_id", claims.UserID)
 					c.Set("user_email", claims.Email)
+					c.Set("user_role", claims.Role)
+					c.Set("session_id", claims.SessionID)
+					if claims.Actor != nil {
+						c.Set("impersonator_id", claims.Actor.UserID)
+					}
 				}
 			}
 		}
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RequireScope makes a route accept personal access tokens that grant scope.
+// Requests signed in with an access token are not limited by scopes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("personal_access_token")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		if pat, ok := value.(*entities.PersonalAccessToken); !ok || !pat.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "token is missing the " + scope + " scope"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DenyImpersonation blocks the route for admins impersonating a user. It guards
+// account changes only the user should make, and must run after AuthMiddleware.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonating := c.Get("impersonator_id"); impersonating {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating a user"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole only lets through users whose role is at least role. It must run
+// after AuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRole, exists := c.Get("user_role")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+
+		if !entities.RoleAtLeast(userRole.(string), role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func sessionActive(c *gin.Context, sessionRepo repositories.SessionRepository, sessionID string) bool {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false
+	}
+
+	session, err := sessionRepo.GetByID(c.Request.Context(), objectID)
+	return err == nil && session.IsActive()
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditEventRepository(db *database.MongoDB) repositories.AuditEventRepository {
+	return &auditEventRepository{
+		collection: db.GetCollection("audit_events"),
+	}
+}
+
+func (r *auditEventRepository) Create(ctx context.Context, event *entities.AuditEvent) error {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *auditEventRepository) List(ctx context.Context, filter entities.AuditEventFilter, limit, offset int) ([]*entities.AuditEvent, error) {
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Email != "" {
+		query["email"] = strings.ToLower(filter.Email)
+	}
+	if filter.IPAddress != "" {
+		query["ip_address"] = filter.IPAddress
+	}
+	if filter.Since != nil || filter.Until != nil {
+		createdAt := bson.M{}
+		if filter.Since != nil {
+			createdAt["$gte"] = *filter.Since
+		}
+		if filter.Until != nil {
+			createdAt["$lt"] = *filter.Until
+		}
+		query["created_at"] = createdAt
+	}
+
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []*entities.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *auditEventRepository) GetByUserID(ctx context.Context, userID string) ([]*entities.AuditEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []*entities.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *auditEventRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete audit events: %w", err)
+	}
+
+	return nil
+}
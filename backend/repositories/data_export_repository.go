@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type dataExportRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDataExportRepository(db *database.MongoDB) repositories.DataExportRepository {
+	return &dataExportRepository{
+		collection: db.GetCollection("data_exports"),
+	}
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, export *entities.DataExport) error {
+	export.ID = primitive.NewObjectID()
+	export.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, export)
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+
+	return nil
+}
+
+func (r *dataExportRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.DataExport, error) {
+	var export entities.DataExport
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&export)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("export not found")
+		}
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+
+	return &export, nil
+}
+
+func (r *dataExportRepository) GetLatestByUserID(ctx context.Context, userID string) (*entities.DataExport, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var export entities.DataExport
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID}, opts).Decode(&export)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("export not found")
+		}
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+
+	return &export, nil
+}
+
+func (r *dataExportRepository) GetByUserID(ctx context.Context, userID string) ([]*entities.DataExport, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var exports []*entities.DataExport
+	if err := cursor.All(ctx, &exports); err != nil {
+		return nil, fmt.Errorf("failed to decode exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+func (r *dataExportRepository) Update(ctx context.Context, export *entities.DataExport) error {
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": export.ID}, export)
+	if err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("export not found")
+	}
+
+	return nil
+}
+
+func (r *dataExportRepository) GetExpired(ctx context.Context, now time.Time) ([]*entities.DataExport, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var exports []*entities.DataExport
+	if err := cursor.All(ctx, &exports); err != nil {
+		return nil, fmt.Errorf("failed to decode exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+func (r *dataExportRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("failed to delete export: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type inviteRepository struct {
+	collection *mongo.Collection
+}
+
+func NewInviteRepository(db *database.MongoDB) repositories.InviteRepository {
+	return &inviteRepository{
+		collection: db.GetCollection("invites"),
+	}
+}
+
+func (r *inviteRepository) Create(ctx context.Context, invite *entities.Invite) error {
+	invite.ID = primitive.NewObjectID()
+	invite.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, invite)
+	if err != nil {
+		return fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	return nil
+}
+
+func (r *inviteRepository) GetByCodeHash(ctx context.Context, codeHash string) (*entities.Invite, error) {
+	var invite entities.Invite
+	err := r.collection.FindOne(ctx, bson.M{"code_hash": codeHash}).Decode(&invite)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("invite not found")
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	return &invite, nil
+}
+
+func (r *inviteRepository) List(ctx context.Context, limit, offset int) ([]*entities.Invite, error) {
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	invites := []*entities.Invite{}
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, fmt.Errorf("failed to decode invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+func (r *inviteRepository) Redeem(ctx context.Context, id primitive.ObjectID) error {
+	// The conditions are part of the update so concurrent sign-ups cannot overuse it
+	filter := bson.M{
+		"_id":        id,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+		"$expr":      bson.M{"$lt": bson.A{"$uses", "$max_uses"}},
+	}
+	update := bson.M{"$inc": bson.M{"uses": 1}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to redeem invite: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("invite is no longer valid")
+	}
+
+	return nil
+}
+
+func (r *inviteRepository) Release(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "uses": bson.M{"$gt": 0}}
+	update := bson.M{"$inc": bson.M{"uses": -1}}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to release invite: %w", err)
+	}
+
+	return nil
+}
+
+func (r *inviteRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("invite not found")
+	}
+
+	return nil
+}
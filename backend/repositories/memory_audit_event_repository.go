@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryAuditEventRepository struct {
+	store *memoryStore
+}
+
+func NewMemoryAuditEventRepository(store *memoryStore) domainrepo.AuditEventRepository {
+	return &memoryAuditEventRepository{store: store}
+}
+
+func (r *memoryAuditEventRepository) Create(_ context.Context, event *entities.AuditEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+
+	// Events are appended in order, so the slice stays sorted oldest first
+	r.store.auditEvents = append(r.store.auditEvents, cloneAuditEvent(event))
+	return nil
+}
+
+func (r *memoryAuditEventRepository) List(_ context.Context, filter entities.AuditEventFilter, limit, offset int) ([]*entities.AuditEvent, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	events := []*entities.AuditEvent{}
+	for i := len(r.store.auditEvents) - 1; i >= 0; i-- {
+		event := r.store.auditEvents[i]
+		if filter.UserID != "" && event.UserID != filter.UserID {
+			continue
+		}
+		if filter.Type != "" && event.Type != filter.Type {
+			continue
+		}
+		if filter.Email != "" && event.Email != strings.ToLower(filter.Email) {
+			continue
+		}
+		if filter.IPAddress != "" && event.IPAddress != filter.IPAddress {
+			continue
+		}
+		if filter.Since != nil && event.CreatedAt.Before(*filter.Since) {
+			continue
+		}
+		if filter.Until != nil && !event.CreatedAt.Before(*filter.Until) {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(events) {
+		return []*entities.AuditEvent{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(events) {
+		end = len(events)
+	}
+
+	page := make([]*entities.AuditEvent, 0, end-offset)
+	for _, event := range events[offset:end] {
+		page = append(page, cloneAuditEvent(event))
+	}
+	return page, nil
+}
+
+func (r *memoryAuditEventRepository) GetByUserID(ctx context.Context, userID string) ([]*entities.AuditEvent, error) {
+	return r.List(ctx, entities.AuditEventFilter{UserID: userID}, 0, 0)
+}
+
+func (r *memoryAuditEventRepository) DeleteByUserID(_ context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.auditEvents[:0]
+	for _, event := range r.store.auditEvents {
+		if event.UserID != userID {
+			kept = append(kept, event)
+		}
+	}
+	r.store.auditEvents = kept
+	return nil
+}
+
+func cloneAuditEvent(event *entities.AuditEvent) *entities.AuditEvent {
+	copyValue := *event
+	if event.Details != nil {
+		copyValue.Details = make(map[string]string, len(event.Details))
+		for key, value := range event.Details {
+			copyValue.Details[key] = value
+		}
+	}
+	return &copyValue
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryDataExportRepository struct {
+	store *memoryStore
+}
+
+func NewMemoryDataExportRepository(store *memoryStore) domainrepo.DataExportRepository {
+	return &memoryDataExportRepository{store: store}
+}
+
+func (r *memoryDataExportRepository) Create(_ context.Context, export *entities.DataExport) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	export.ID = primitive.NewObjectID()
+	export.CreatedAt = time.Now()
+
+	clone := *export
+	r.store.dataExports[export.ID] = &clone
+	return nil
+}
+
+func (r *memoryDataExportRepository) GetByID(_ context.Context, id primitive.ObjectID) (*entities.DataExport, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	export, ok := r.store.dataExports[id]
+	if !ok {
+		return nil, fmt.Errorf("export not found")
+	}
+
+	clone := *export
+	return &clone, nil
+}
+
+func (r *memoryDataExportRepository) GetLatestByUserID(_ context.Context, userID string) (*entities.DataExport, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var latest *entities.DataExport
+	for _, export := range r.store.dataExports {
+		if export.UserID == userID && (latest == nil || export.CreatedAt.After(latest.CreatedAt)) {
+			latest = export
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("export not found")
+	}
+
+	clone := *latest
+	return &clone, nil
+}
+
+func (r *memoryDataExportRepository) GetByUserID(_ context.Context, userID string) ([]*entities.DataExport, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var exports []*entities.DataExport
+	for _, export := range r.store.dataExports {
+		if export.UserID == userID {
+			clone := *export
+			exports = append(exports, &clone)
+		}
+	}
+
+	return exports, nil
+}
+
+func (r *memoryDataExportRepository) Update(_ context.Context, export *entities.DataExport) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.dataExports[export.ID]; !ok {
+		return fmt.Errorf("export not found")
+	}
+
+	clone := *export
+	r.store.dataExports[export.ID] = &clone
+	return nil
+}
+
+func (r *memoryDataExportRepository) GetExpired(_ context.Context, now time.Time) ([]*entities.DataExport, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var exports []*entities.DataExport
+	for _, export := range r.store.dataExports {
+		if !now.Before(export.ExpiresAt) {
+			clone := *export
+			exports = append(exports, &clone)
+		}
+	}
+
+	return exports, nil
+}
+
+func (r *memoryDataExportRepository) Delete(_ context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.dataExports, id)
+	return nil
+}
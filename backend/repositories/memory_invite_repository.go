@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryInviteRepository struct {
+	store *memoryStore
+}
+
+func NewMemoryInviteRepository(store *memoryStore) domainrepo.InviteRepository {
+	return &memoryInviteRepository{store: store}
+}
+
+func (r *memoryInviteRepository) Create(_ context.Context, invite *entities.Invite) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	invite.ID = primitive.NewObjectID()
+	invite.CreatedAt = time.Now()
+
+	copyValue := *invite
+	r.store.invites[invite.ID] = &copyValue
+	return nil
+}
+
+func (r *memoryInviteRepository) GetByCodeHash(_ context.Context, codeHash string) (*entities.Invite, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, invite := range r.store.invites {
+		if invite.CodeHash == codeHash {
+			copyValue := *invite
+			return &copyValue, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invite not found")
+}
+
+func (r *memoryInviteRepository) List(_ context.Context, limit, offset int) ([]*entities.Invite, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	invites := make([]*entities.Invite, 0, len(r.store.invites))
+	for _, invite := range r.store.invites {
+		copyValue := *invite
+		invites = append(invites, &copyValue)
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.After(invites[j].CreatedAt)
+	})
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(invites) {
+		return []*entities.Invite{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(invites) {
+		end = len(invites)
+	}
+
+	return invites[offset:end], nil
+}
+
+func (r *memoryInviteRepository) Redeem(_ context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	invite, ok := r.store.invites[id]
+	if !ok || !invite.IsUsable() {
+		return fmt.Errorf("invite is no longer valid")
+	}
+
+	invite.Uses++
+	return nil
+}
+
+func (r *memoryInviteRepository) Release(_ context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if invite, ok := r.store.invites[id]; ok && invite.Uses > 0 {
+		invite.Uses--
+	}
+	return nil
+}
+
+func (r *memoryInviteRepository) Revoke(_ context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	invite, ok := r.store.invites[id]
+	if !ok || invite.RevokedAt != nil {
+		return fmt.Errorf("invite not found")
+	}
+
+	now := time.Now()
+	invite.RevokedAt = &now
+	return nil
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+)
+
+type memoryLoginAttemptRepository struct {
+	store *memoryStore
+}
+
+func NewMemoryLoginAttemptRepository(store *memoryStore) domainrepo.LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{store: store}
+}
+
+func (r *memoryLoginAttemptRepository) Get(_ context.Context, key string) (*entities.LoginAttempt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	attempt, ok := r.store.loginAttempts[key]
+	if !ok || !time.Now().Before(attempt.ExpiresAt) {
+		return nil, fmt.Errorf("login attempt not found")
+	}
+
+	return cloneLoginAttempt(attempt), nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(_ context.Context, key string, expiresAt time.Time) (*entities.LoginAttempt, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+
+	// Drop expired records so keys from abandoned attempts do not pile up
+	for k, attempt := range r.store.loginAttempts {
+		if !now.Before(attempt.ExpiresAt) {
+			delete(r.store.loginAttempts, k)
+		}
+	}
+
+	attempt, ok := r.store.loginAttempts[key]
+	if !ok {
+		attempt = &entities.LoginAttempt{Key: key}
+		r.store.loginAttempts[key] = attempt
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	attempt.ExpiresAt = expiresAt
+
+	return cloneLoginAttempt(attempt), nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(_ context.Context, key string, until time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if attempt, ok := r.store.loginAttempts[key]; ok {
+		attempt.LockedUntil = &until
+	}
+
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(_ context.Context, key string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.loginAttempts, key)
+	return nil
+}
+
+func cloneLoginAttempt(attempt *entities.LoginAttempt) *entities.LoginAttempt {
+	clone := *attempt
+	if attempt.LockedUntil != nil {
+		lockedUntil := *attempt.LockedUntil
+		clone.LockedUntil = &lockedUntil
+	}
+	return &clone
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryOneTimeTokenRepository struct {
+	store *memoryStore
+}
+
+func NewMemoryOneTimeTokenRepository(store *memoryStore) domainrepo.OneTimeTokenRepository {
+	return &memoryOneTimeTokenRepository{store: store}
+}
+
+func (r *memoryOneTimeTokenRepository) Create(_ context.Context, token *entities.OneTimeToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	// Drop expired tokens so abandoned ceremonies and links do not pile up
+	for id, stored := range r.store.oneTimeTokens {
+		if !token.CreatedAt.Before(stored.ExpiresAt) {
+			delete(r.store.oneTimeTokens, id)
+		}
+	}
+
+	clone := *token
+	r.store.oneTimeTokens[token.ID] = &clone
+	return nil
+}
+
+func (r *memoryOneTimeTokenRepository) GetByHash(_ context.Context, tokenHash, purpose string) (*entities.OneTimeToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.oneTimeTokens {
+		if token.TokenHash == tokenHash && token.Purpose == purpose {
+			clone := *token
+			return &clone, nil
+		}
+	}
+
+	return nil, fmt.Errorf("token not found")
+}
+
+func (r *memoryOneTimeTokenRepository) Consume(_ context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.oneTimeTokens[id]
+	if !ok || token.UsedAt != nil {
+		return fmt.Errorf("token already used")
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	return nil
+}
+
+func (r *memoryOneTimeTokenRepository) DeleteByUserID(_ context.Context, userID, purpose string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, token := range r.store.oneTimeTokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(r.store.oneTimeTokens, id)
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryOneTimeTokenRepository) DeleteAllByUserID(_ context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, token := range r.store.oneTimeTokens {
+		if token.UserID == userID {
+			delete(r.store.oneTimeTokens, id)
+		}
+	}
+
+	return nil
+}
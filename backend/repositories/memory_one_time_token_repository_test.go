@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"devfolio-backend/domain/entities"
+)
+
+func TestMemoryOneTimeTokensDropExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryOneTimeTokenRepository(NewMemoryStore())
+
+	expired := &entities.OneTimeToken{Purpose: entities.TokenPurposeMagicLink, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)}
+	live := &entities.OneTimeToken{Purpose: entities.TokenPurposeMagicLink, TokenHash: "live", ExpiresAt: time.Now().Add(time.Hour)}
+	for _, token := range []*entities.OneTimeToken{expired, live} {
+		if err := repo.Create(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := repo.GetByHash(ctx, "expired", entities.TokenPurposeMagicLink); err == nil {
+		t.Fatal("GetByHash() found a token that expired before the next one was stored")
+	}
+	if _, err := repo.GetByHash(ctx, "live", entities.TokenPurposeMagicLink); err != nil {
+		t.Fatalf("GetByHash() error = %v", err)
+	}
+}
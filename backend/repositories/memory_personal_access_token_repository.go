@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryPersonalAccessTokenRepository struct {
+	store *memoryStore
+}
+
+func NewMemoryPersonalAccessTokenRepository(store *memoryStore) domainrepo.PersonalAccessTokenRepository {
+	return &memoryPersonalAccessTokenRepository{store: store}
+}
+
+func (r *memoryPersonalAccessTokenRepository) Create(_ context.Context, token *entities.PersonalAccessToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	r.store.accessTokens[token.ID] = clonePersonalAccessToken(token)
+	return nil
+}
+
+func (r *memoryPersonalAccessTokenRepository) GetByHash(_ context.Context, tokenHash string) (*entities.PersonalAccessToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.accessTokens {
+		if token.TokenHash == tokenHash {
+			return clonePersonalAccessToken(token), nil
+		}
+	}
+
+	return nil, fmt.Errorf("token not found")
+}
+
+func (r *memoryPersonalAccessTokenRepository) GetByUserID(_ context.Context, userID string) ([]*entities.PersonalAccessToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tokens := []*entities.PersonalAccessToken{}
+	for _, token := range r.store.accessTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, clonePersonalAccessToken(token))
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (r *memoryPersonalAccessTokenRepository) UpdateLastUsed(_ context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if token, ok := r.store.accessTokens[id]; ok {
+		token.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+func (r *memoryPersonalAccessTokenRepository) Delete(_ context.Context, id primitive.ObjectID, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.accessTokens[id]
+	if !ok || token.UserID != userID {
+		return fmt.Errorf("token not found")
+	}
+
+	delete(r.store.accessTokens, id)
+	return nil
+}
+
+func (r *memoryPersonalAccessTokenRepository) DeleteByUserID(_ context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, token := range r.store.accessTokens {
+		if token.UserID == userID {
+			delete(r.store.accessTokens, id)
+		}
+	}
+
+	return nil
+}
+
+func clonePersonalAccessToken(token *entities.PersonalAccessToken) *entities.PersonalAccessToken {
+	copyValue := *token
+	copyValue.Scopes = append([]string(nil), token.Scopes...)
+	return &copyValue
+}
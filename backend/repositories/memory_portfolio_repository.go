@@ -123,6 +123,52 @@ func (r *memoryPortfolioRepository) Search(_ context.Context, query string, limi
 	return paginatePortfolios(portfolios, limit, offset), nil
 }
 
+func (r *memoryPortfolioRepository) ListAll(_ context.Context, userID, query string, limit, offset int) ([]*entities.Portfolio, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	query = strings.ToLower(query)
+	var portfolios []*entities.Portfolio
+
+	for _, portfolio := range r.store.portfolios {
+		if userID != "" && portfolio.UserID != userID {
+			continue
+		}
+		if query == "" || matchesPortfolioQuery(portfolio, query) {
+			portfolios = append(portfolios, clonePortfolio(portfolio))
+		}
+	}
+
+	sortPortfolios(portfolios)
+	return paginatePortfolios(portfolios, limit, offset), nil
+}
+
+func (r *memoryPortfolioRepository) DeleteByUserID(_ context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, portfolio := range r.store.portfolios {
+		if portfolio.UserID == userID {
+			delete(r.store.portfolios, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryPortfolioRepository) UnpublishByUserID(_ context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, portfolio := range r.store.portfolios {
+		if portfolio.UserID == userID && portfolio.IsPublic {
+			portfolio.IsPublic = false
+			portfolio.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
 func matchesPortfolioQuery(portfolio *entities.Portfolio, query string) bool {
 	if strings.Contains(strings.ToLower(portfolio.Name), query) ||
 		strings.Contains(strings.ToLower(portfolio.Title), query) ||
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memorySessionRepository struct {
+	store *memoryStore
+}
+
+func NewMemorySessionRepository(store *memoryStore) domainrepo.SessionRepository {
+	return &memorySessionRepository{store: store}
+}
+
+func (r *memorySessionRepository) Create(_ context.Context, session *entities.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = time.Now()
+	session.LastUsedAt = session.CreatedAt
+
+	clone := *session
+	r.store.sessions[session.ID] = &clone
+	return nil
+}
+
+func (r *memorySessionRepository) GetByID(_ context.Context, id primitive.ObjectID) (*entities.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	session, ok := r.store.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	clone := *session
+	return &clone, nil
+}
+
+func (r *memorySessionRepository) GetActiveByUserID(_ context.Context, userID string) ([]*entities.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var sessions []*entities.Session
+	for _, session := range r.store.sessions {
+		if session.UserID == userID && session.IsActive() {
+			clone := *session
+			sessions = append(sessions, &clone)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) Rotate(_ context.Context, id primitive.ObjectID, oldHash, newHash string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.sessions[id]
+	if !ok || session.RevokedAt != nil || session.RefreshTokenHash != oldHash {
+		return fmt.Errorf("session token mismatch")
+	}
+
+	session.RefreshTokenHash = newHash
+	session.LastUsedAt = time.Now()
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+func (r *memorySessionRepository) Revoke(_ context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.sessions[id]
+	if !ok || session.RevokedAt != nil {
+		return fmt.Errorf("session not found")
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeAllByUserID(_ context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range r.store.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+
+	return nil
+}
@@ -14,12 +14,28 @@ type memoryStore struct {
 	usersByKey map[string]primitive.ObjectID
 
 	portfolios map[primitive.ObjectID]*entities.Portfolio
+
+	sessions      map[primitive.ObjectID]*entities.Session
+	oneTimeTokens map[primitive.ObjectID]*entities.OneTimeToken
+	accessTokens  map[primitive.ObjectID]*entities.PersonalAccessToken
+	dataExports   map[primitive.ObjectID]*entities.DataExport
+	invites       map[primitive.ObjectID]*entities.Invite
+
+	loginAttempts map[string]*entities.LoginAttempt
+
+	auditEvents []*entities.AuditEvent
 }
 
 func NewMemoryStore() *memoryStore {
 	return &memoryStore{
-		users:      make(map[primitive.ObjectID]*entities.User),
-		usersByKey: make(map[string]primitive.ObjectID),
-		portfolios: make(map[primitive.ObjectID]*entities.Portfolio),
+		users:         make(map[primitive.ObjectID]*entities.User),
+		usersByKey:    make(map[string]primitive.ObjectID),
+		portfolios:    make(map[primitive.ObjectID]*entities.Portfolio),
+		sessions:      make(map[primitive.ObjectID]*entities.Session),
+		oneTimeTokens: make(map[primitive.ObjectID]*entities.OneTimeToken),
+		accessTokens:  make(map[primitive.ObjectID]*entities.PersonalAccessToken),
+		dataExports:   make(map[primitive.ObjectID]*entities.DataExport),
+		invites:       make(map[primitive.ObjectID]*entities.Invite),
+		loginAttempts: make(map[string]*entities.LoginAttempt),
 	}
 }
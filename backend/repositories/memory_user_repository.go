@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,14 +24,19 @@ func (r *memoryUserRepository) Create(_ context.Context, user *entities.User) er
 	r.store.mu.Lock()
 	defer r.store.mu.Unlock()
 
-	user.ID = primitive.NewObjectID()
 	user.Email = strings.ToLower(user.Email)
+	user.EmailKey = entities.CanonicalEmail(user.Email)
+	if r.emailKeyInUse(user.EmailKey, primitive.NilObjectID) {
+		return fmt.Errorf("email already registered")
+	}
+
+	user.ID = primitive.NewObjectID()
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = user.CreatedAt
 	user.IsActive = true
 
-	clone := *user
-	r.store.users[user.ID] = &clone
+	clone := cloneUser(user)
+	r.store.users[user.ID] = clone
 	r.store.usersByKey[clone.Email] = clone.ID
 	return nil
 }
@@ -44,26 +50,42 @@ func (r *memoryUserRepository) GetByID(_ context.Context, id primitive.ObjectID)
 		return nil, fmt.Errorf("user not found")
 	}
 
-	clone := *user
-	return &clone, nil
+	return cloneUser(user), nil
 }
 
 func (r *memoryUserRepository) GetByEmail(_ context.Context, email string) (*entities.User, error) {
 	r.store.mu.RLock()
 	defer r.store.mu.RUnlock()
 
-	id, ok := r.store.usersByKey[strings.ToLower(email)]
-	if !ok {
-		return nil, fmt.Errorf("user not found")
+	if id, ok := r.store.usersByKey[strings.ToLower(email)]; ok {
+		if user, ok := r.store.users[id]; ok && user.IsActive {
+			return cloneUser(user), nil
+		}
 	}
 
-	user, ok := r.store.users[id]
-	if !ok || !user.IsActive {
+	user := r.findByEmailKey(entities.CanonicalEmail(email), func(user *entities.User) bool { return user.IsActive })
+	if user == nil {
 		return nil, fmt.Errorf("user not found")
 	}
+	return cloneUser(user), nil
+}
+
+func (r *memoryUserRepository) GetByIdentity(_ context.Context, provider, subject string) (*entities.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if !user.IsActive {
+			continue
+		}
+		for _, identity := range user.Identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				return cloneUser(user), nil
+			}
+		}
+	}
 
-	clone := *user
-	return &clone, nil
+	return nil, fmt.Errorf("user not found")
 }
 
 func (r *memoryUserRepository) Update(_ context.Context, id primitive.ObjectID, user *entities.User) error {
@@ -77,11 +99,18 @@ func (r *memoryUserRepository) Update(_ context.Context, id primitive.ObjectID,
 
 	user.ID = id
 	user.Email = strings.ToLower(user.Email)
+	user.EmailKey = entities.CanonicalEmail(user.Email)
+	if r.emailKeyInUse(user.EmailKey, id) {
+		return fmt.Errorf("email is already in use by another account")
+	}
 	user.CreatedAt = existing.CreatedAt
 	user.UpdatedAt = time.Now()
 
-	clone := *user
-	r.store.users[id] = &clone
+	clone := cloneUser(user)
+	r.store.users[id] = clone
+	if existing.Email != clone.Email && r.store.usersByKey[existing.Email] == id {
+		delete(r.store.usersByKey, existing.Email)
+	}
 	r.store.usersByKey[clone.Email] = clone.ID
 	return nil
 }
@@ -101,6 +130,19 @@ func (r *memoryUserRepository) UpdateLastLogin(_ context.Context, id primitive.O
 	return nil
 }
 
+func (r *memoryUserRepository) UpdateKnownDevices(_ context.Context, id primitive.ObjectID, devices []entities.KnownDevice) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok || !user.IsActive {
+		return fmt.Errorf("user not found")
+	}
+
+	user.KnownDevices = append([]entities.KnownDevice(nil), devices...)
+	return nil
+}
+
 func (r *memoryUserRepository) Delete(_ context.Context, id primitive.ObjectID) error {
 	r.store.mu.Lock()
 	defer r.store.mu.Unlock()
@@ -119,11 +161,181 @@ func (r *memoryUserRepository) EmailExists(_ context.Context, email string) (boo
 	r.store.mu.RLock()
 	defer r.store.mu.RUnlock()
 
-	id, ok := r.store.usersByKey[strings.ToLower(email)]
+	if id, ok := r.store.usersByKey[strings.ToLower(email)]; ok {
+		if user, ok := r.store.users[id]; ok && user.IsActive {
+			return true, nil
+		}
+	}
+
+	return r.emailKeyInUse(entities.CanonicalEmail(email), primitive.NilObjectID), nil
+}
+
+func (r *memoryUserRepository) GetByIDIncludingInactive(_ context.Context, id primitive.ObjectID) (*entities.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
 	if !ok {
-		return false, nil
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return cloneUser(user), nil
+}
+
+func (r *memoryUserRepository) List(_ context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	query := strings.ToLower(filter.Query)
+	var users []*entities.User
+	for _, user := range r.store.users {
+		if filter.Role != "" && user.EffectiveRole() != filter.Role {
+			continue
+		}
+		if (filter.Status == "active" && !user.IsActive) || (filter.Status == "inactive" && user.IsActive) {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(user.Email), query) &&
+			!strings.Contains(strings.ToLower(user.FirstName), query) &&
+			!strings.Contains(strings.ToLower(user.LastName), query) {
+			continue
+		}
+		users = append(users, cloneUser(user))
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+
+	if offset < 0 {
+		offset = 0
 	}
+	if offset >= len(users) {
+		return []*entities.User{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(users) {
+		end = len(users)
+	}
+	return users[offset:end], nil
+}
+
+func (r *memoryUserRepository) SetActive(_ context.Context, id primitive.ObjectID, active bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
 
 	user, ok := r.store.users[id]
-	return ok && user.IsActive, nil
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	if active && !user.IsActive && r.emailKeyInUse(entities.CanonicalEmail(user.Email), id) {
+		return fmt.Errorf("email is already in use by another account")
+	}
+
+	user.IsActive = active
+	user.UpdatedAt = time.Now()
+	if active {
+		r.store.usersByKey[user.Email] = user.ID
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) GetPendingDeletionByEmail(_ context.Context, email string) (*entities.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	email = strings.ToLower(email)
+	for _, user := range r.store.users {
+		if user.Email == email && user.PendingDeletion() {
+			return cloneUser(user), nil
+		}
+	}
+
+	user := r.findByEmailKey(entities.CanonicalEmail(email), (*entities.User).PendingDeletion)
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return cloneUser(user), nil
+}
+
+func (r *memoryUserRepository) DeactivatedEmailExists(_ context.Context, email string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	email = strings.ToLower(email)
+	key := entities.CanonicalEmail(email)
+	for _, user := range r.store.users {
+		if user.IsActive || user.DeletionScheduledAt != nil {
+			continue
+		}
+		if user.Email == email || entities.CanonicalEmail(user.Email) == key {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *memoryUserRepository) GetDueForDeletion(_ context.Context, cutoff time.Time) ([]*entities.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var users []*entities.User
+	for _, user := range r.store.users {
+		if !user.IsActive && user.DeletionScheduledAt != nil && !user.DeletionScheduledAt.After(cutoff) {
+			users = append(users, cloneUser(user))
+		}
+	}
+
+	return users, nil
+}
+
+func (r *memoryUserRepository) Purge(_ context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+
+	delete(r.store.users, id)
+	if r.store.usersByKey[user.Email] == id {
+		delete(r.store.usersByKey, user.Email)
+	}
+	return nil
+}
+
+// findByEmailKey returns the oldest user with the canonical email key that
+// matches, or nil. The caller must hold the store lock.
+func (r *memoryUserRepository) findByEmailKey(key string, match func(user *entities.User) bool) *entities.User {
+	var found *entities.User
+	for _, user := range r.store.users {
+		if match(user) && entities.CanonicalEmail(user.Email) == key &&
+			(found == nil || user.CreatedAt.Before(found.CreatedAt)) {
+			found = user
+		}
+	}
+	return found
+}
+
+// emailKeyInUse reports whether an active account other than except has the
+// canonical email key. The caller must hold the store lock.
+func (r *memoryUserRepository) emailKeyInUse(key string, except primitive.ObjectID) bool {
+	for id, user := range r.store.users {
+		if id != except && user.IsActive && entities.CanonicalEmail(user.Email) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneUser(user *entities.User) *entities.User {
+	copyValue := *user
+	copyValue.RecoveryCodeHashes = append([]string(nil), user.RecoveryCodeHashes...)
+	copyValue.Passkeys = append([]entities.PasskeyCredential(nil), user.Passkeys...)
+	copyValue.Identities = append([]entities.LinkedIdentity(nil), user.Identities...)
+	copyValue.KnownDevices = append([]entities.KnownDevice(nil), user.KnownDevices...)
+	return &copyValue
 }
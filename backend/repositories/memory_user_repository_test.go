@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"devfolio-backend/domain/entities"
+)
+
+func TestMemoryUserLookupByCanonicalEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository(NewMemoryStore())
+
+	user := &entities.User{Email: "ab@gmail.com"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.GetByEmail(ctx, "A.B+work@gmail.com")
+	if err != nil || found.ID != user.ID {
+		t.Fatalf("GetByEmail() = %v, %v, want %v", found, err, user.ID)
+	}
+	if _, err := repo.GetByEmail(ctx, "ab@example.com"); err == nil {
+		t.Fatal("GetByEmail() found a user at another domain")
+	}
+	if err := repo.Create(ctx, &entities.User{Email: "a.b@googlemail.com"}); err == nil {
+		t.Fatal("Create() accepted a second account for the same address")
+	}
+
+	scheduledAt := time.Now().Add(time.Hour)
+	found.DeletionScheduledAt = &scheduledAt
+	if err := repo.Update(ctx, user.ID, found); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetActive(ctx, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.GetByEmail(ctx, "a.b@gmail.com"); err == nil {
+		t.Fatal("GetByEmail() found a deleted account")
+	}
+	pending, err := repo.GetPendingDeletionByEmail(ctx, "a.b@gmail.com")
+	if err != nil || pending.ID != user.ID {
+		t.Fatalf("GetPendingDeletionByEmail() = %v, %v, want %v", pending, err, user.ID)
+	}
+}
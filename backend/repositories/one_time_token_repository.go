@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type oneTimeTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOneTimeTokenRepository(db *database.MongoDB) repositories.OneTimeTokenRepository {
+	repo := &oneTimeTokenRepository{
+		collection: db.GetCollection("one_time_tokens"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := repo.createIndexes(ctx); err != nil {
+		log.Printf("Failed to create one-time token indexes: %v", err)
+	}
+
+	return repo
+}
+
+// createIndexes lets MongoDB delete tokens once they expire, used or not, and
+// look tokens up by hash without scanning the collection.
+func (r *oneTimeTokenRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	return nil
+}
+
+func (r *oneTimeTokenRepository) Create(ctx context.Context, token *entities.OneTimeToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *oneTimeTokenRepository) GetByHash(ctx context.Context, tokenHash, purpose string) (*entities.OneTimeToken, error) {
+	var token entities.OneTimeToken
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash, "purpose": purpose}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("token not found")
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *oneTimeTokenRepository) Consume(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "used_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"used_at": time.Now()}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("token already used")
+	}
+
+	return nil
+}
+
+func (r *oneTimeTokenRepository) DeleteByUserID(ctx context.Context, userID, purpose string) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose}); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *oneTimeTokenRepository) DeleteAllByUserID(ctx context.Context, userID string) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type personalAccessTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPersonalAccessTokenRepository(db *database.MongoDB) repositories.PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{
+		collection: db.GetCollection("personal_access_tokens"),
+	}
+}
+
+func (r *personalAccessTokenRepository) Create(ctx context.Context, token *entities.PersonalAccessToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *personalAccessTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entities.PersonalAccessToken, error) {
+	var token entities.PersonalAccessToken
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("token not found")
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *personalAccessTokenRepository) GetByUserID(ctx context.Context, userID string) ([]*entities.PersonalAccessToken, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokens: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []*entities.PersonalAccessToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (r *personalAccessTokenRepository) UpdateLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	update := bson.M{"$set": bson.M{"last_used_at": usedAt}}
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *personalAccessTokenRepository) Delete(ctx context.Context, id primitive.ObjectID, userID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("token not found")
+	}
+
+	return nil
+}
+
+func (r *personalAccessTokenRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	return nil
+}
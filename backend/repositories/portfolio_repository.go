@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"devfolio-backend/domain/entities"
@@ -155,3 +156,54 @@ func (r *portfolioRepository) Search(ctx context.Context, query string, limit, o
 
 	return portfolios, nil
 }
+
+func (r *portfolioRepository) ListAll(ctx context.Context, userID, query string, limit, offset int) ([]*entities.Portfolio, error) {
+	filter := bson.M{}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	if query != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(query), "$options": "i"}
+		filter["$or"] = []bson.M{
+			{"name": pattern},
+			{"title": pattern},
+			{"bio": pattern},
+			{"skills": pattern},
+		}
+	}
+
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list portfolios: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var portfolios []*entities.Portfolio
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, fmt.Errorf("failed to decode portfolios: %w", err)
+	}
+
+	return portfolios, nil
+}
+
+func (r *portfolioRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete portfolios: %w", err)
+	}
+
+	return nil
+}
+
+func (r *portfolioRepository) UnpublishByUserID(ctx context.Context, userID string) error {
+	update := bson.M{"$set": bson.M{"is_public": false, "updated_at": time.Now()}}
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"user_id": userID, "is_public": true}, update); err != nil {
+		return fmt.Errorf("failed to unpublish portfolios: %w", err)
+	}
+
+	return nil
+}
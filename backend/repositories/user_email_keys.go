@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"devfolio-backend/domain/entities"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrateEmailKeys builds the unique email key index and gives accounts
+// created before email keys existed their key.
+//
+// Older data can hold active accounts whose addresses only differ in ways
+// CanonicalEmail ignores. The oldest of them keeps the key and the others are
+// left without one, which the partial index does not cover. They keep working
+// under their exact address, and each collision is logged so an admin can
+// merge or deactivate the duplicates.
+func (r *userRepository) migrateEmailKeys(ctx context.Context) error {
+	if err := r.resolveDuplicateEmailKeys(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "email_key", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"is_active": true,
+			"email_key": bson.M{"$type": "string"},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create unique email key index: %w", err)
+	}
+
+	return r.backfillEmailKeys(ctx)
+}
+
+// resolveDuplicateEmailKeys removes the key from all but the oldest active
+// account sharing one, so the unique index can be built. Keys are only
+// duplicated if an earlier index build failed.
+func (r *userRepository) resolveDuplicateEmailKeys(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"is_active": true, "email_key": bson.M{"$type": "string"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$email_key",
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to find duplicate email keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var group struct {
+			Key string               `bson:"_id"`
+			IDs []primitive.ObjectID `bson:"ids"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return fmt.Errorf("failed to decode duplicate email keys: %w", err)
+		}
+
+		duplicates := group.IDs[1:]
+		update := bson.M{"$unset": bson.M{"email_key": ""}}
+		if _, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}}, update); err != nil {
+			return fmt.Errorf("failed to remove duplicate email key %s: %w", group.Key, err)
+		}
+		log.Printf("Email key collision: accounts %v share the address of account %s and were left without an email key", hexIDs(duplicates), group.IDs[0].Hex())
+	}
+
+	return cursor.Err()
+}
+
+// backfillEmailKeys sets the key on active accounts that have none, oldest
+// first. The unique index refuses keys another account already holds.
+func (r *userRepository) backfillEmailKeys(ctx context.Context) error {
+	filter := bson.M{"is_active": true, "email_key": bson.M{"$exists": false}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetProjection(bson.M{"email": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find users without an email key: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	backfilled := 0
+	for cursor.Next(ctx) {
+		var user entities.User
+		if err := cursor.Decode(&user); err != nil {
+			return fmt.Errorf("failed to decode user: %w", err)
+		}
+
+		update := bson.M{"$set": bson.M{"email_key": entities.CanonicalEmail(user.Email)}}
+		_, err := r.collection.UpdateOne(ctx, bson.M{"_id": user.ID, "email_key": bson.M{"$exists": false}}, update)
+		if mongo.IsDuplicateKeyError(err) {
+			log.Printf("Email key collision: account %s shares its address with an older account and was left without an email key", user.ID.Hex())
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set email key for user %s: %w", user.ID.Hex(), err)
+		}
+		backfilled++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if backfilled > 0 {
+		log.Printf("Set email keys on %d existing accounts", backfilled)
+	}
+	return nil
+}
+
+func hexIDs(ids []primitive.ObjectID) []string {
+	hex := make([]string, len(ids))
+	for i, id := range ids {
+		hex[i] = id.Hex()
+	}
+	return hex
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"log"
+	"regexp"
 	"time"
 
 	"devfolio-backend/domain/entities"
@@ -12,6 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type userRepository struct {
@@ -19,9 +22,18 @@ type userRepository struct {
 }
 
 func NewUserRepository(db *database.MongoDB) repositories.UserRepository {
-	return &userRepository{
+	repo := &userRepository{
 		collection: db.GetCollection("users"),
 	}
+
+	// Existing accounts may need their keys backfilled, so allow some time
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if err := repo.migrateEmailKeys(ctx); err != nil {
+		log.Printf("Failed to migrate email keys: %v", err)
+	}
+
+	return repo
 }
 
 func (r *userRepository) Create(ctx context.Context, user *entities.User) error {
@@ -29,12 +41,16 @@ func (r *userRepository) Create(ctx context.Context, user *entities.User) error
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 	user.IsActive = true
+	user.EmailKey = entities.CanonicalEmail(user.Email)
 	if user.AuthProvider == "" {
 		user.AuthProvider = "local"
 	}
 
 	_, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("email already registered")
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -55,8 +71,45 @@ func (r *userRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*e
 }
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	return r.findByEmail(ctx, email, bson.M{"is_active": true})
+}
+
+// findByEmail finds a user matching filter by exact address, or else by the
+// canonical key. The exact match goes first so accounts that were left
+// without a key by migrateEmailKeys can still be found.
+func (r *userRepository) findByEmail(ctx context.Context, email string, filter bson.M) (*entities.User, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	for _, match := range []bson.M{{"email": email}, {"email_key": entities.CanonicalEmail(email)}} {
+		query := bson.M{}
+		for key, value := range filter {
+			query[key] = value
+		}
+		for key, value := range match {
+			query[key] = value
+		}
+
+		var user entities.User
+		err := r.collection.FindOne(ctx, query, opts).Decode(&user)
+		if err == nil {
+			return &user, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("user not found")
+}
+
+func (r *userRepository) GetByIdentity(ctx context.Context, provider, subject string) (*entities.User, error) {
+	filter := bson.M{
+		"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}},
+		"is_active":  true,
+	}
+
 	var user entities.User
-	err := r.collection.FindOne(ctx, bson.M{"email": email, "is_active": true}).Decode(&user)
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, fmt.Errorf("user not found")
@@ -69,6 +122,8 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entitie
 
 func (r *userRepository) Update(ctx context.Context, id primitive.ObjectID, user *entities.User) error {
 	user.UpdatedAt = time.Now()
+	hadKey := user.EmailKey != ""
+	user.EmailKey = entities.CanonicalEmail(user.Email)
 
 	updateDoc, err := bson.Marshal(user)
 	if err != nil {
@@ -83,7 +138,17 @@ func (r *userRepository) Update(ctx context.Context, id primitive.ObjectID, user
 
 	update := bson.M{"$set": setFields}
 	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "is_active": true}, update)
+	if mongo.IsDuplicateKeyError(err) && !hadKey {
+		// A legacy account sharing its address with an older one stays
+		// without a key, rather than failing every update
+		user.EmailKey = ""
+		delete(setFields, "email_key")
+		result, err = r.collection.UpdateOne(ctx, bson.M{"_id": id, "is_active": true}, update)
+	}
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("email is already in use by another account")
+		}
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
@@ -110,6 +175,21 @@ func (r *userRepository) UpdateLastLogin(ctx context.Context, id primitive.Objec
 	return nil
 }
 
+func (r *userRepository) UpdateKnownDevices(ctx context.Context, id primitive.ObjectID, devices []entities.KnownDevice) error {
+	update := bson.M{"$set": bson.M{"known_devices": devices}}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "is_active": true}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update known devices: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
 func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	// Soft delete by setting is_active to false
 	update := bson.M{"$set": bson.M{"is_active": false, "updated_at": time.Now()}}
@@ -126,10 +206,147 @@ func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID) erro
 }
 
 func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, error) {
-	count, err := r.collection.CountDocuments(ctx, bson.M{"email": email, "is_active": true})
+	filter := bson.M{
+		"is_active": true,
+		"$or": bson.A{
+			bson.M{"email": email},
+			bson.M{"email_key": entities.CanonicalEmail(email)},
+		},
+	}
+	count, err := r.collection.CountDocuments(ctx, filter)
 	if err != nil {
 		return false, fmt.Errorf("failed to check email existence: %w", err)
 	}
 
 	return count > 0, nil
 }
+
+func (r *userRepository) GetByIDIncludingInactive(ctx context.Context, id primitive.ObjectID) (*entities.User, error) {
+	var user entities.User
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) List(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.User, error) {
+	query := bson.M{}
+	if filter.Query != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(filter.Query), "$options": "i"}
+		query["$or"] = []bson.M{
+			{"email": pattern},
+			{"first_name": pattern},
+			{"last_name": pattern},
+		}
+	}
+	switch filter.Role {
+	case "":
+	case entities.RoleUser:
+		// Users created before roles existed have no role field
+		query["role"] = bson.M{"$in": []interface{}{entities.RoleUser, "", nil}}
+	default:
+		query["role"] = filter.Role
+	}
+	switch filter.Status {
+	case "active":
+		query["is_active"] = true
+	case "inactive":
+		query["is_active"] = false
+	}
+
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []*entities.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) SetActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	update := bson.M{"$set": bson.M{"is_active": active, "updated_at": time.Now()}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+func (r *userRepository) Purge(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+func (r *userRepository) GetPendingDeletionByEmail(ctx context.Context, email string) (*entities.User, error) {
+	filter := bson.M{
+		"is_active":             false,
+		"deletion_scheduled_at": bson.M{"$gt": time.Now()},
+	}
+
+	return r.findByEmail(ctx, email, filter)
+}
+
+func (r *userRepository) DeactivatedEmailExists(ctx context.Context, email string) (bool, error) {
+	filter := bson.M{
+		"is_active":             false,
+		"deletion_scheduled_at": nil,
+		"$or": bson.A{
+			bson.M{"email": email},
+			bson.M{"email_key": entities.CanonicalEmail(email)},
+		},
+	}
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to check deactivated email: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *userRepository) GetDueForDeletion(ctx context.Context, cutoff time.Time) ([]*entities.User, error) {
+	filter := bson.M{
+		"is_active":             false,
+		"deletion_scheduled_at": bson.M{"$lte": cutoff},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users due for deletion: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []*entities.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/mail"
+	"devfolio-backend/infrastructure/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccountUsecase handles a user's own account as a whole, such as deleting it.
+type AccountUsecase interface {
+	// RequestEmailChange mails a confirmation link to the new address and a
+	// notice to the current one.
+	RequestEmailChange(ctx context.Context, userID, sessionID string, req *entities.ChangeEmailRequest, client *entities.ClientInfo) error
+	ConfirmEmailChange(ctx context.Context, token string, client *entities.ClientInfo) (*entities.User, error)
+	DeleteAccount(ctx context.Context, userID, sessionID string, req *entities.ReauthenticateRequest, client *entities.ClientInfo) (*entities.AccountDeletionResponse, error)
+	// PurgeDeletedAccounts removes the data of accounts whose grace period is over.
+	PurgeDeletedAccounts(ctx context.Context) (int, error)
+	// RunPurger calls PurgeDeletedAccounts periodically until ctx is done.
+	RunPurger(ctx context.Context)
+}
+
+type accountUsecase struct {
+	authUsecase      AuthUsecase
+	userRepo         repositories.UserRepository
+	portfolioRepo    repositories.PortfolioRepository
+	sessionRepo      repositories.SessionRepository
+	tokenRepo        repositories.PersonalAccessTokenRepository
+	oneTimeTokenRepo repositories.OneTimeTokenRepository
+	auditRepo        repositories.AuditEventRepository
+	audit            *auditLog
+	purger           *accountPurger
+	registration     *registrationPolicy
+	mailer           mail.Mailer
+	frontendURL      string
+	emailChangeTTL   time.Duration
+	gracePeriod      time.Duration
+	purgeInterval    time.Duration
+}
+
+func NewAccountUsecase(
+	authUsecase AuthUsecase,
+	userRepo repositories.UserRepository,
+	portfolioRepo repositories.PortfolioRepository,
+	sessionRepo repositories.SessionRepository,
+	tokenRepo repositories.PersonalAccessTokenRepository,
+	oneTimeTokenRepo repositories.OneTimeTokenRepository,
+	exportRepo repositories.DataExportRepository,
+	attemptRepo repositories.LoginAttemptRepository,
+	auditRepo repositories.AuditEventRepository,
+	exportFiles *storage.FileStore,
+	mailer mail.Mailer,
+	cfg *config.Config,
+) (AccountUsecase, error) {
+	// Email change links last as long as verification links
+	emailChangeTTL, err := time.ParseDuration(cfg.Auth.EmailVerificationExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email verification expiry: %w", err)
+	}
+
+	gracePeriod, err := time.ParseDuration(cfg.Auth.AccountDeletionGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account deletion grace period: %w", err)
+	}
+
+	purgeInterval, err := time.ParseDuration(cfg.Auth.AccountPurgeInterval)
+	if err != nil || purgeInterval <= 0 {
+		return nil, fmt.Errorf("invalid account purge interval %q", cfg.Auth.AccountPurgeInterval)
+	}
+
+	// Only the address checks are used here, which need no invites
+	registration, err := newRegistrationPolicy(nil, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &accountUsecase{
+		authUsecase:      authUsecase,
+		userRepo:         userRepo,
+		portfolioRepo:    portfolioRepo,
+		sessionRepo:      sessionRepo,
+		tokenRepo:        tokenRepo,
+		oneTimeTokenRepo: oneTimeTokenRepo,
+		auditRepo:        auditRepo,
+		audit:            newAuditLog(auditRepo),
+		registration:     registration,
+		mailer:           mailer,
+		frontendURL:      strings.TrimRight(cfg.CORS.FrontendURL, "/"),
+		emailChangeTTL:   emailChangeTTL,
+		gracePeriod:      gracePeriod,
+		purgeInterval:    purgeInterval,
+		purger: &accountPurger{
+			userRepo:         userRepo,
+			portfolioRepo:    portfolioRepo,
+			sessionRepo:      sessionRepo,
+			tokenRepo:        tokenRepo,
+			oneTimeTokenRepo: oneTimeTokenRepo,
+			exportRepo:       exportRepo,
+			attemptRepo:      attemptRepo,
+			auditRepo:        auditRepo,
+			exportFiles:      exportFiles,
+		},
+	}, nil
+}
+
+// RequestEmailChange does not check whether the new address is taken, so the
+// response never tells whether another account uses it. That is checked when
+// the change is confirmed.
+func (u *accountUsecase) RequestEmailChange(ctx context.Context, userID, sessionID string, req *entities.ChangeEmailRequest, client *entities.ClientInfo) error {
+	user, err := u.authUsecase.Reauthenticate(ctx, userID, sessionID, &req.ReauthenticateRequest, client)
+	if err != nil {
+		return err
+	}
+
+	newEmail := strings.ToLower(strings.TrimSpace(req.NewEmail))
+	if newEmail == user.Email {
+		return fmt.Errorf("new email is the same as the current one")
+	}
+	if err := u.registration.checkAddress(newEmail); err != nil {
+		return err
+	}
+
+	// Only the latest change link should work
+	if err := u.oneTimeTokenRepo.DeleteByUserID(ctx, userID, entities.TokenPurposeEmailChange); err != nil {
+		return fmt.Errorf("failed to clear previous email change tokens: %w", err)
+	}
+
+	token, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate email change token: %w", err)
+	}
+
+	changeToken := &entities.OneTimeToken{
+		UserID:    userID,
+		Email:     newEmail,
+		Purpose:   entities.TokenPurposeEmailChange,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(u.emailChangeTTL),
+	}
+	if err := u.oneTimeTokenRepo.Create(ctx, changeToken); err != nil {
+		return fmt.Errorf("failed to store email change token: %w", err)
+	}
+
+	link := u.frontendURL + "/confirm-email-change?token=" + url.QueryEscape(token)
+	if err := u.mailer.Send(ctx, &mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new DevFolio email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nOpen the link below to start using this address for your DevFolio account:\n\n%s\n\nThe link expires in %s. If you did not ask for this, you can ignore this email.\n",
+			user.FirstName, link, u.emailChangeTTL,
+		),
+	}); err != nil {
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	// The old address is told too, in case someone else is signed in as the user
+	notice := &mail.Message{
+		To:      user.Email,
+		Subject: "Your DevFolio email address is being changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to change the email address of your DevFolio account to %s. The change only happens once the link sent to that address is opened.\n\nIf this was not you, change your password and sign out of all sessions right away.\n",
+			user.FirstName, newEmail,
+		),
+	}
+	go func() {
+		if err := u.mailer.Send(context.Background(), notice); err != nil {
+			fmt.Printf("Failed to send email change notice to user %s: %v\n", userID, err)
+		}
+	}()
+
+	return nil
+}
+
+func (u *accountUsecase) ConfirmEmailChange(ctx context.Context, token string, client *entities.ClientInfo) (*entities.User, error) {
+	changeToken, err := u.oneTimeTokenRepo.GetByHash(ctx, auth.HashToken(token), entities.TokenPurposeEmailChange)
+	if err != nil || !changeToken.IsUsable() {
+		return nil, fmt.Errorf("invalid or expired email change token")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(changeToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired email change token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired email change token")
+	}
+
+	taken, err := emailTaken(ctx, u.userRepo, changeToken.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken {
+		return nil, fmt.Errorf("email is already in use by another account")
+	}
+
+	if err := u.oneTimeTokenRepo.Consume(ctx, changeToken.ID); err != nil {
+		return nil, fmt.Errorf("invalid or expired email change token")
+	}
+
+	// Opening the link proves the new address belongs to the user
+	oldEmail := user.Email
+	user.Email = changeToken.Email
+	user.IsVerified = true
+	user.VerificationTokenID = ""
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditEmailChanged,
+		UserID:  changeToken.UserID,
+		Email:   user.Email,
+		Details: map[string]string{"old_email": oldEmail},
+	})
+
+	// Portfolios that show a different contact address were set that way on purpose
+	portfolios, err := u.portfolioRepo.GetByUserID(ctx, changeToken.UserID)
+	if err != nil {
+		fmt.Printf("Failed to get portfolios of user %s: %v\n", changeToken.UserID, err)
+	}
+	for _, portfolio := range portfolios {
+		if !strings.EqualFold(portfolio.Email, oldEmail) {
+			continue
+		}
+		portfolio.Email = user.Email
+		if err := u.portfolioRepo.Update(ctx, portfolio.ID, portfolio); err != nil {
+			fmt.Printf("Failed to update email of portfolio %s: %v\n", portfolio.ID.Hex(), err)
+		}
+	}
+
+	return user, nil
+}
+
+// DeleteAccount deactivates the account right away, hides its portfolios and
+// signs it out everywhere. The data is purged once the grace period is over,
+// unless the user signs in again before then.
+func (u *accountUsecase) DeleteAccount(ctx context.Context, userID, sessionID string, req *entities.ReauthenticateRequest, client *entities.ClientInfo) (*entities.AccountDeletionResponse, error) {
+	user, err := u.authUsecase.Reauthenticate(ctx, userID, sessionID, req, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.portfolioRepo.UnpublishByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to unpublish portfolios: %w", err)
+	}
+
+	scheduledAt := time.Now().Add(u.gracePeriod)
+	user.DeletionScheduledAt = &scheduledAt
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+	if err := u.userRepo.SetActive(ctx, user.ID, false); err != nil {
+		return nil, fmt.Errorf("failed to deactivate account: %w", err)
+	}
+
+	if err := u.sessionRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		fmt.Printf("Failed to revoke sessions for user %s: %v\n", userID, err)
+	}
+	// Scripts must not keep working, even if the account is restored
+	if err := u.tokenRepo.DeleteByUserID(ctx, userID); err != nil {
+		fmt.Printf("Failed to delete access tokens for user %s: %v\n", userID, err)
+	}
+
+	return &entities.AccountDeletionResponse{DeletionScheduledAt: scheduledAt}, nil
+}
+
+func (u *accountUsecase) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	users, err := u.userRepo.GetDueForDeletion(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range users {
+		if err := u.purger.purge(ctx, user); err != nil {
+			fmt.Printf("Failed to purge account %s: %v\n", user.ID.Hex(), err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (u *accountUsecase) RunPurger(ctx context.Context) {
+	ticker := time.NewTicker(u.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := u.PurgeDeletedAccounts(ctx); err != nil {
+			fmt.Printf("Failed to purge deleted accounts: %v\n", err)
+		} else if purged > 0 {
+			fmt.Printf("Purged %d deleted accounts\n", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// accountPurger permanently removes a user together with everything stored
+// about them.
+type accountPurger struct {
+	userRepo         repositories.UserRepository
+	portfolioRepo    repositories.PortfolioRepository
+	sessionRepo      repositories.SessionRepository
+	tokenRepo        repositories.PersonalAccessTokenRepository
+	oneTimeTokenRepo repositories.OneTimeTokenRepository
+	exportRepo       repositories.DataExportRepository
+	attemptRepo      repositories.LoginAttemptRepository
+	auditRepo        repositories.AuditEventRepository
+	exportFiles      *storage.FileStore
+}
+
+// purge deletes the user document last, so a failed purge is retried on the
+// next run.
+func (p *accountPurger) purge(ctx context.Context, user *entities.User) error {
+	userID := user.ID.Hex()
+
+	if err := p.sessionRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		fmt.Printf("Failed to revoke sessions for user %s: %v\n", userID, err)
+	}
+	if err := p.tokenRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete user tokens: %w", err)
+	}
+	if err := p.oneTimeTokenRepo.DeleteAllByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete user one-time tokens: %w", err)
+	}
+	if err := p.deleteExports(ctx, userID); err != nil {
+		return err
+	}
+	if err := p.attemptRepo.Reset(ctx, emailAttemptKey(user.Email)); err != nil {
+		return fmt.Errorf("failed to delete user login attempts: %w", err)
+	}
+	if err := p.portfolioRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete user portfolios: %w", err)
+	}
+	if err := p.auditRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete user audit events: %w", err)
+	}
+	if err := p.userRepo.Purge(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
+
+// deleteExports removes the user's data exports, archive first so no file is
+// left behind without a record pointing to it.
+func (p *accountPurger) deleteExports(ctx context.Context, userID string) error {
+	exports, err := p.exportRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user exports: %w", err)
+	}
+
+	for _, export := range exports {
+		if export.FileName != "" {
+			if err := p.exportFiles.Remove(export.FileName); err != nil {
+				return fmt.Errorf("failed to remove export archive %s: %w", export.ID.Hex(), err)
+			}
+		}
+		if err := p.exportRepo.Delete(ctx, export.ID); err != nil {
+			return fmt.Errorf("failed to delete export %s: %w", export.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/storage"
+	"devfolio-backend/repositories"
+)
+
+func TestPurgeRemovesEverythingAboutTheUser(t *testing.T) {
+	ctx := context.Background()
+	store := repositories.NewMemoryStore()
+	files := storage.NewFileStore(t.TempDir())
+	purger := &accountPurger{
+		userRepo:         repositories.NewMemoryUserRepository(store),
+		portfolioRepo:    repositories.NewMemoryPortfolioRepository(store),
+		sessionRepo:      repositories.NewMemorySessionRepository(store),
+		tokenRepo:        repositories.NewMemoryPersonalAccessTokenRepository(store),
+		oneTimeTokenRepo: repositories.NewMemoryOneTimeTokenRepository(store),
+		exportRepo:       repositories.NewMemoryDataExportRepository(store),
+		attemptRepo:      repositories.NewMemoryLoginAttemptRepository(store),
+		auditRepo:        repositories.NewMemoryAuditEventRepository(store),
+		exportFiles:      files,
+	}
+
+	user := &entities.User{Email: "leaving@example.com", Role: entities.RoleUser}
+	if err := purger.userRepo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	userID := user.ID.Hex()
+
+	resetToken := &entities.OneTimeToken{UserID: userID, Purpose: entities.TokenPurposePasswordReset, TokenHash: "reset", ExpiresAt: time.Now().Add(time.Hour)}
+	changeToken := &entities.OneTimeToken{UserID: userID, Purpose: entities.TokenPurposeEmailChange, TokenHash: "change", ExpiresAt: time.Now().Add(time.Hour)}
+	for _, token := range []*entities.OneTimeToken{resetToken, changeToken} {
+		if err := purger.oneTimeTokenRepo.Create(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	export := &entities.DataExport{UserID: userID, Status: entities.DataExportReady, FileName: "export.zip", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := purger.exportRepo.Create(ctx, export); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := files.Create(export.FileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	if _, err := purger.attemptRepo.RecordFailure(ctx, emailAttemptKey(user.Email), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := purger.purge(ctx, user); err != nil {
+		t.Fatalf("purge() error = %v", err)
+	}
+
+	if _, err := purger.userRepo.GetByIDIncludingInactive(ctx, user.ID); err == nil {
+		t.Error("user still exists")
+	}
+	for _, token := range []*entities.OneTimeToken{resetToken, changeToken} {
+		if _, err := purger.oneTimeTokenRepo.GetByHash(ctx, token.TokenHash, token.Purpose); err == nil {
+			t.Errorf("%s token still exists", token.Purpose)
+		}
+	}
+	if exports, err := purger.exportRepo.GetByUserID(ctx, userID); err != nil || len(exports) != 0 {
+		t.Errorf("exports = %d, %v, want none", len(exports), err)
+	}
+	path, err := files.Path(export.FileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("export archive still exists: %v", err)
+	}
+	if _, err := purger.attemptRepo.Get(ctx, emailAttemptKey(user.Email)); err == nil {
+		t.Error("login attempts still exist")
+	}
+}
+
+func TestRequestEmailChangeAppliesRegistrationPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	blocklist := filepath.Join(t.TempDir(), "disposable.txt")
+	if err := os.WriteFile(blocklist, []byte("example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ta := newTestAuth(t, func(cfg *config.Config) {
+		cfg.Registration.Mode = entities.RegistrationDomain
+		cfg.Registration.AllowedDomains = "devfolio.test,mail.example.com"
+		cfg.Registration.DisposableDomainsFile = blocklist
+	})
+
+	store := repositories.NewMemoryStore()
+	accounts, err := NewAccountUsecase(
+		ta,
+		ta.userRepo,
+		repositories.NewMemoryPortfolioRepository(store),
+		ta.sessionRepo,
+		repositories.NewMemoryPersonalAccessTokenRepository(store),
+		ta.tokenRepo,
+		repositories.NewMemoryDataExportRepository(store),
+		repositories.NewMemoryLoginAttemptRepository(store),
+		repositories.NewMemoryAuditEventRepository(store),
+		storage.NewFileStore(t.TempDir()),
+		discardMailer{},
+		ta.cfg,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := registerRequest("member@devfolio.test", "")
+	user, _, err := ta.Register(ctx, req, &entities.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change := func(newEmail string) error {
+		return accounts.RequestEmailChange(ctx, user.ID.Hex(), "", &entities.ChangeEmailRequest{
+			NewEmail:              newEmail,
+			ReauthenticateRequest: entities.ReauthenticateRequest{Password: req.Password},
+		}, &entities.ClientInfo{})
+	}
+
+	if err := change("member@mail.example.com"); !errors.Is(err, ErrDisposableEmail) {
+		t.Errorf("change to a disposable address: error = %v, want ErrDisposableEmail", err)
+	}
+	if err := change("member@elsewhere.test"); !errors.Is(err, ErrEmailDomainNotAllowed) {
+		t.Errorf("change to a domain outside the policy: error = %v, want ErrEmailDomainNotAllowed", err)
+	}
+	if err := change("new@devfolio.test"); err != nil {
+		t.Errorf("change to an allowed address: error = %v", err)
+	}
+}
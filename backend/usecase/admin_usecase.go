@@ -0,0 +1,396 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminUsecase lets moderators and admins manage other users' accounts and
+// portfolios. Callers are expected to have checked the role already.
+type AdminUsecase interface {
+	ListUsers(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.AdminUserResponse, error)
+	GetUser(ctx context.Context, id string) (*entities.AdminUserResponse, error)
+	SetUserRole(ctx context.Context, actorID, id, role string) (*entities.AdminUserResponse, error)
+	DeactivateUser(ctx context.Context, actorID, id string) error
+	ReactivateUser(ctx context.Context, id string) error
+	DeleteUser(ctx context.Context, actorID, id string) error
+	ListPortfolios(ctx context.Context, userID, query string, limit, offset int) ([]*entities.Portfolio, error)
+	UpdatePortfolio(ctx context.Context, actorID, id string, req *entities.UpdatePortfolioRequest, client *entities.ClientInfo) (*entities.Portfolio, error)
+	DeletePortfolio(ctx context.Context, id string) error
+	// ImpersonateUser issues a short-lived access token that lets an admin see
+	// and do what the user can, apart from sensitive account changes.
+	ImpersonateUser(ctx context.Context, actorID, id, reason string, client *entities.ClientInfo) (*entities.ImpersonationResponse, error)
+	// PromoteAdmins gives the admin role to the verified accounts with these emails.
+	PromoteAdmins(ctx context.Context, emails []string)
+}
+
+// ErrAdminSelfAction is returned when an admin tries to change their own role
+// or status, which could lock the last admin out.
+var ErrAdminSelfAction = errors.New("you cannot change your own account through the admin API")
+
+// ErrImpersonationNotAllowed is returned for accounts that cannot be
+// impersonated, which would hand out their permissions.
+var ErrImpersonationNotAllowed = errors.New("only active accounts with the user role can be impersonated")
+
+type adminUsecase struct {
+	userRepo      repositories.UserRepository
+	portfolioRepo repositories.PortfolioRepository
+	sessionRepo   repositories.SessionRepository
+	tokenRepo     repositories.PersonalAccessTokenRepository
+	auditRepo     repositories.AuditEventRepository
+	audit         *auditLog
+	purger        *accountPurger
+	jwtManager    *auth.JWTManager
+	// impersonationTTL is how long an impersonation token and its session last
+	impersonationTTL time.Duration
+}
+
+func NewAdminUsecase(
+	userRepo repositories.UserRepository,
+	portfolioRepo repositories.PortfolioRepository,
+	sessionRepo repositories.SessionRepository,
+	tokenRepo repositories.PersonalAccessTokenRepository,
+	oneTimeTokenRepo repositories.OneTimeTokenRepository,
+	exportRepo repositories.DataExportRepository,
+	attemptRepo repositories.LoginAttemptRepository,
+	auditRepo repositories.AuditEventRepository,
+	exportFiles *storage.FileStore,
+	jwtManager *auth.JWTManager,
+	cfg *config.Config,
+) (AdminUsecase, error) {
+	impersonationTTL, err := time.ParseDuration(cfg.Auth.ImpersonationExpiry)
+	if err != nil || impersonationTTL <= 0 {
+		return nil, fmt.Errorf("invalid impersonation expiry %q", cfg.Auth.ImpersonationExpiry)
+	}
+
+	return &adminUsecase{
+		userRepo:         userRepo,
+		portfolioRepo:    portfolioRepo,
+		sessionRepo:      sessionRepo,
+		tokenRepo:        tokenRepo,
+		auditRepo:        auditRepo,
+		audit:            newAuditLog(auditRepo),
+		jwtManager:       jwtManager,
+		impersonationTTL: impersonationTTL,
+		purger: &accountPurger{
+			userRepo:         userRepo,
+			portfolioRepo:    portfolioRepo,
+			sessionRepo:      sessionRepo,
+			tokenRepo:        tokenRepo,
+			oneTimeTokenRepo: oneTimeTokenRepo,
+			exportRepo:       exportRepo,
+			attemptRepo:      attemptRepo,
+			auditRepo:        auditRepo,
+			exportFiles:      exportFiles,
+		},
+	}, nil
+}
+
+func (u *adminUsecase) ListUsers(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.AdminUserResponse, error) {
+	if filter.Role != "" && !entities.IsValidRole(filter.Role) {
+		return nil, fmt.Errorf("unknown role %q", filter.Role)
+	}
+	if filter.Status != "" && filter.Status != "active" && filter.Status != "inactive" {
+		return nil, fmt.Errorf("status must be active or inactive")
+	}
+	limit, offset = clampPage(limit, offset)
+
+	users, err := u.userRepo.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	responses := make([]*entities.AdminUserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, user.ToAdminResponse())
+	}
+	return responses, nil
+}
+
+func (u *adminUsecase) GetUser(ctx context.Context, id string) (*entities.AdminUserResponse, error) {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return user.ToAdminResponse(), nil
+}
+
+// SetUserRole changes the user's role. A demoted user is signed out everywhere
+// so no access token with the old role stays usable.
+func (u *adminUsecase) SetUserRole(ctx context.Context, actorID, id, role string) (*entities.AdminUserResponse, error) {
+	if !entities.IsValidRole(role) {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+	if actorID == id {
+		return nil, ErrAdminSelfAction
+	}
+
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("reactivate the user before changing their role")
+	}
+
+	previous := user.EffectiveRole()
+	if previous != role {
+		user.Role = role
+		if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+			return nil, fmt.Errorf("failed to update role: %w", err)
+		}
+		if !entities.RoleAtLeast(role, previous) {
+			u.revokeSessions(ctx, user.ID.Hex())
+		}
+	}
+
+	return user.ToAdminResponse(), nil
+}
+
+func (u *adminUsecase) DeactivateUser(ctx context.Context, actorID, id string) error {
+	if actorID == id {
+		return ErrAdminSelfAction
+	}
+
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.SetActive(ctx, user.ID, false); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	u.revokeSessions(ctx, user.ID.Hex())
+	return nil
+}
+
+func (u *adminUsecase) ReactivateUser(ctx context.Context, id string) error {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.IsActive {
+		return nil
+	}
+	if user.DeletionScheduledAt != nil && !user.PendingDeletion() {
+		return fmt.Errorf("the account's deletion grace period is over")
+	}
+
+	// Someone may have registered the address while the account was deactivated
+	exists, err := u.userRepo.EmailExists(ctx, user.Email)
+	if err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("another active account uses %s", user.Email)
+	}
+
+	if err := u.userRepo.SetActive(ctx, user.ID, true); err != nil {
+		return fmt.Errorf("failed to reactivate user: %w", err)
+	}
+
+	// Reactivating also cancels a deletion the user asked for
+	if user.DeletionScheduledAt != nil {
+		user.IsActive = true
+		user.DeletionScheduledAt = nil
+		if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+			return fmt.Errorf("failed to cancel account deletion: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteUser permanently removes the user and their portfolios.
+func (u *adminUsecase) DeleteUser(ctx context.Context, actorID, id string) error {
+	if actorID == id {
+		return ErrAdminSelfAction
+	}
+
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return u.purger.purge(ctx, user)
+}
+
+func (u *adminUsecase) ListPortfolios(ctx context.Context, userID, query string, limit, offset int) ([]*entities.Portfolio, error) {
+	limit, offset = clampPage(limit, offset)
+
+	portfolios, err := u.portfolioRepo.ListAll(ctx, userID, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list portfolios: %w", err)
+	}
+
+	return portfolios, nil
+}
+
+// UpdatePortfolio edits any portfolio. Unlike the owner's update it may publish
+// a portfolio without checking the owner's email verification.
+func (u *adminUsecase) UpdatePortfolio(ctx context.Context, actorID, id string, req *entities.UpdatePortfolioRequest, client *entities.ClientInfo) (*entities.Portfolio, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid portfolio ID: %w", err)
+	}
+
+	existing, err := u.portfolioRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
+	wasPublic := existing.IsPublic
+	applyPortfolioUpdate(existing, req)
+	if req.IsPublic != nil {
+		existing.IsPublic = *req.IsPublic
+	}
+
+	if err := u.portfolioRepo.Update(ctx, objectID, existing); err != nil {
+		return nil, fmt.Errorf("failed to update portfolio: %w", err)
+	}
+
+	if existing.IsPublic != wasPublic {
+		u.audit.record(ctx, client, portfolioVisibilityEvent(existing, actorID))
+	}
+
+	return existing, nil
+}
+
+func (u *adminUsecase) DeletePortfolio(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid portfolio ID: %w", err)
+	}
+
+	if err := u.portfolioRepo.Delete(ctx, objectID); err != nil {
+		return fmt.Errorf("failed to delete portfolio: %w", err)
+	}
+
+	return nil
+}
+
+// ImpersonateUser starts a session for the user that belongs to the admin. It
+// shows up in the user's session list, can be revoked like any other and has no
+// refresh token, so it ends when the access token expires.
+func (u *adminUsecase) ImpersonateUser(ctx context.Context, actorID, id, reason string, client *entities.ClientInfo) (*entities.ImpersonationResponse, error) {
+	if actorID == id {
+		return nil, ErrAdminSelfAction
+	}
+
+	actor, err := u.getUser(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Impersonating staff would hand their role to the actor
+	if !user.IsActive || user.EffectiveRole() != entities.RoleUser {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	session := &entities.Session{
+		UserID:         user.ID.Hex(),
+		ExpiresAt:      time.Now().Add(u.impersonationTTL),
+		ImpersonatorID: actorID,
+	}
+	if client != nil {
+		session.IPAddress = client.IPAddress
+		session.UserAgent = client.UserAgent
+	}
+	if err := u.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	actorClaim := auth.ActorClaim{UserID: actorID, Email: actor.Email}
+	token, err := u.jwtManager.GenerateImpersonationToken(user.ID.Hex(), user.Email, user.EffectiveRole(), session.ID.Hex(), actorClaim, u.impersonationTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditImpersonationStarted,
+		UserID:  user.ID.Hex(),
+		ActorID: actorID,
+		Email:   user.Email,
+		Details: map[string]string{"reason": reason, "session_id": session.ID.Hex()},
+	})
+
+	return &entities.ImpersonationResponse{
+		AccessToken: token,
+		ExpiresAt:   session.ExpiresAt,
+		User:        user.ToResponse(),
+	}, nil
+}
+
+func (u *adminUsecase) PromoteAdmins(ctx context.Context, emails []string) {
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+
+		user, err := u.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			fmt.Printf("Admin account %s does not exist yet\n", email)
+			continue
+		}
+		// Anyone can register an unverified address, so it proves nothing
+		if !user.IsVerified {
+			fmt.Printf("Not promoting %s to admin until the email address is verified\n", email)
+			continue
+		}
+		if user.Role == entities.RoleAdmin {
+			continue
+		}
+
+		user.Role = entities.RoleAdmin
+		if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+			fmt.Printf("Failed to promote %s to admin: %v\n", email, err)
+		}
+	}
+}
+
+func (u *adminUsecase) getUser(ctx context.Context, id string) (*entities.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := u.userRepo.GetByIDIncludingInactive(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (u *adminUsecase) revokeSessions(ctx context.Context, userID string) {
+	if err := u.sessionRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		fmt.Printf("Failed to revoke sessions for user %s: %v\n", userID, err)
+	}
+}
+
+func clampPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
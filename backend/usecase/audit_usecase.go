@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+)
+
+// AuditUsecase reads the audit log: users see their own activity and admins can
+// search all of it.
+type AuditUsecase interface {
+	ListActivity(ctx context.Context, userID string, limit, offset int) ([]*entities.AuditEvent, error)
+	ListEvents(ctx context.Context, filter entities.AuditEventFilter, limit, offset int) ([]*entities.AuditEvent, error)
+}
+
+type auditUsecase struct {
+	auditRepo repositories.AuditEventRepository
+}
+
+func NewAuditUsecase(auditRepo repositories.AuditEventRepository) AuditUsecase {
+	return &auditUsecase{auditRepo: auditRepo}
+}
+
+func (u *auditUsecase) ListActivity(ctx context.Context, userID string, limit, offset int) ([]*entities.AuditEvent, error) {
+	limit, offset = clampPage(limit, offset)
+
+	events, err := u.auditRepo.List(ctx, entities.AuditEventFilter{UserID: userID}, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+
+	return events, nil
+}
+
+func (u *auditUsecase) ListEvents(ctx context.Context, filter entities.AuditEventFilter, limit, offset int) ([]*entities.AuditEvent, error) {
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return nil, fmt.Errorf("since must be before until")
+	}
+	limit, offset = clampPage(limit, offset)
+
+	events, err := u.auditRepo.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// auditLog records audit events for the use cases that emit them.
+type auditLog struct {
+	repo repositories.AuditEventRepository
+}
+
+func newAuditLog(repo repositories.AuditEventRepository) *auditLog {
+	return &auditLog{repo: repo}
+}
+
+// portfolioVisibilityEvent describes a portfolio being published or hidden.
+// actorID is empty when the owner made the change.
+func portfolioVisibilityEvent(portfolio *entities.Portfolio, actorID string) *entities.AuditEvent {
+	visibility := "private"
+	if portfolio.IsPublic {
+		visibility = "public"
+	}
+
+	return &entities.AuditEvent{
+		Type:    entities.AuditPortfolioVisibilityChanged,
+		UserID:  portfolio.UserID,
+		ActorID: actorID,
+		Details: map[string]string{"portfolio_id": portfolio.ID.Hex(), "visibility": visibility},
+	}
+}
+
+// record stores the event with the client it came from. A failure is logged
+// and never fails the action being audited.
+func (a *auditLog) record(ctx context.Context, client *entities.ClientInfo, event *entities.AuditEvent) {
+	if client != nil {
+		event.IPAddress = client.IPAddress
+		event.UserAgent = client.UserAgent
+		// Whatever an admin does while impersonating is attributed to them
+		if event.ActorID == "" {
+			event.ActorID = client.ImpersonatorID
+		}
+	}
+	event.Email = strings.ToLower(event.Email)
+
+	// The event is written even if the client has already gone away
+	if err := a.repo.Create(context.WithoutCancel(ctx), event); err != nil {
+		fmt.Printf("Failed to record %s audit event for user %s: %v\n", event.Type, event.UserID, err)
+	}
+}
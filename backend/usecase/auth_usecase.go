@@ -2,63 +2,269 @@ package usecase
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	"devfolio-backend/domain/entities"
 	"devfolio-backend/domain/repositories"
 	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/mail"
 	
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AuthUsecase interface {
-	Register(ctx context.Context, req *entities.RegisterRequest) (*entities.User, *auth.TokenPair, error)
-	Login(ctx context.Context, req *entities.LoginRequest) (*entities.User, *auth.TokenPair, error)
-	LoginWithGoogle(ctx context.Context, profile *entities.GoogleProfile) (*entities.User, *auth.TokenPair, error)
-	RefreshToken(ctx context.Context, refreshToken string) (string, error)
+	Register(ctx context.Context, req *entities.RegisterRequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error)
+	Login(ctx context.Context, req *entities.LoginRequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error)
+	// LoginWithProvider signs in with a provider identity. inviteCode is only
+	// used if a new account has to be created while registration is invite-only.
+	LoginWithProvider(ctx context.Context, profile *entities.ExternalProfile, inviteCode string, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error)
+	BeginIdentityLink(ctx context.Context, userID, state string) error
+	FinishIdentityLink(ctx context.Context, state string, profile *entities.ExternalProfile) (bool, error)
+	ListIdentities(ctx context.Context, userID string) ([]entities.LinkedIdentity, error)
+	UnlinkIdentity(ctx context.Context, userID, provider string) error
+	Reauthenticate(ctx context.Context, userID, sessionID string, req *entities.ReauthenticateRequest, client *entities.ClientInfo) (*entities.User, error)
+	RefreshToken(ctx context.Context, refreshToken string, client *entities.ClientInfo) (*auth.TokenPair, error)
 	GetProfile(ctx context.Context, userID string) (*entities.User, error)
-	UpdateProfile(ctx context.Context, userID string, req *entities.UpdateProfileRequest) (*entities.User, error)
-	ChangePassword(ctx context.Context, userID string, req *entities.ChangePasswordRequest) error
-	Logout(ctx context.Context, userID string) error
+	UpdateProfile(ctx context.Context, userID string, req *entities.UpdateProfileRequest, client *entities.ClientInfo) (*entities.User, error)
+	ChangePassword(ctx context.Context, userID string, req *entities.ChangePasswordRequest, client *entities.ClientInfo) error
+	Logout(ctx context.Context, userID, sessionID string, client *entities.ClientInfo) error
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]*entities.SessionResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, token string) (*entities.User, error)
+	ResendVerification(ctx context.Context, userID string) error
+	ForgotPassword(ctx context.Context, email string) error
+	// RequestMagicLink emails a single-use sign-in link, which also signs up
+	// addresses that have no account yet. It returns a LoginThrottledError when
+	// the address or client IP asked too often.
+	RequestMagicLink(ctx context.Context, req *entities.MagicLinkRequest, client *entities.ClientInfo) error
+	LoginWithMagicLink(ctx context.Context, token, inviteCode string, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error)
+	ResetPassword(ctx context.Context, req *entities.ResetPasswordRequest, client *entities.ClientInfo) error
+	// SecureAccount handles the "this wasn't me" link sent for a sign-in from a
+	// new device or network.
+	SecureAccount(ctx context.Context, token string, client *entities.ClientInfo) error
+	LoginMFA(ctx context.Context, req *entities.LoginMFARequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error)
+	EnrollTwoFactor(ctx context.Context, userID string) (*entities.TwoFactorSetupResponse, error)
+	ConfirmTwoFactor(ctx context.Context, userID, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, userID, code string) error
+	BeginPasskeyRegistration(ctx context.Context, userID string) (*entities.PasskeyCreationOptions, error)
+	FinishPasskeyRegistration(ctx context.Context, userID string, req *entities.PasskeyRegistrationRequest) (*entities.PasskeyCredential, error)
+	BeginPasskeyLogin(ctx context.Context, client *entities.ClientInfo) (*entities.PasskeyRequestOptions, error)
+	FinishPasskeyLogin(ctx context.Context, req *entities.PasskeyLoginRequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error)
+	ListPasskeys(ctx context.Context, userID string) ([]entities.PasskeyCredential, error)
+	DeletePasskey(ctx context.Context, userID, credentialID string) error
+}
+
+// verificationResendCooldown limits how often a user can ask for a new verification email.
+const verificationResendCooldown = time.Minute
+
+// magicLinkCooldown limits how often a sign-in link is sent to one address,
+// and magicLinkIPLimit how many one client IP can ask for per magicLinkIPWindow.
+const (
+	magicLinkCooldown = time.Minute
+	magicLinkIPLimit  = 10
+	magicLinkIPWindow = time.Hour
+)
+
+// passkeyLoginIPLimit limits how many passkey sign-ins one client IP can start
+// per passkeyLoginIPWindow. Each one stores a challenge.
+const (
+	passkeyLoginIPLimit  = 30
+	passkeyLoginIPWindow = 10 * time.Minute
+)
+
+// identityLinkTimeout matches the lifetime of the provider state cookie.
+const identityLinkTimeout = 10 * time.Minute
+
+// reauthWindow is how recently an account without a password must have signed
+// in to count as re-authenticated.
+const reauthWindow = 5 * time.Minute
+
+// ErrReauthenticationRequired is returned when a sensitive change needs fresh
+// proof of identity that the request did not include.
+var ErrReauthenticationRequired = errors.New("please confirm it's you: enter your password, or sign in again if your account has none")
+
+// ErrPasswordResetRequired is returned when a correct password is used after the
+// user reported a sign-in that was not theirs.
+var ErrPasswordResetRequired = errors.New("this password can no longer be used, reset it with the link sent to your email")
+
+// maxKnownDevices caps a user's device history. The least recently seen device
+// is forgotten first.
+const maxKnownDevices = 20
+
+// ErrLastCredential is returned when removing a sign-in method would leave the
+// account with no way to sign in.
+var ErrLastCredential = errors.New("cannot remove the last way to sign in to this account")
+
+// ErrAccountDeactivated is returned when an administrator deactivated the
+// account. Signing in with its address must not create a new account either.
+var ErrAccountDeactivated = errors.New("account is deactivated")
+
+// LinkRequiredError is returned by LoginWithProvider when the provider's email
+// belongs to an existing account that has not linked this provider.
+type LinkRequiredError struct {
+	Provider string
+	Email    string
+}
+
+func (e *LinkRequiredError) Error() string {
+	return fmt.Sprintf("an account with this email already exists; sign in and link %s from your account settings", e.Provider)
+}
+
+// ErrRefreshTokenReused is returned when an already rotated refresh token is
+// presented again. The whole session is revoked when this happens.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// MFARequiredError is returned by Login when the password was correct but the
+// account also needs a second factor. Token is exchanged through LoginMFA.
+type MFARequiredError struct {
+	Token string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
 }
 
 type authUsecase struct {
-	userRepo        repositories.UserRepository
-	jwtManager      *auth.JWTManager
-	passwordManager *auth.PasswordManager
+	userRepo             repositories.UserRepository
+	sessionRepo          repositories.SessionRepository
+	tokenRepo            repositories.OneTimeTokenRepository
+	jwtManager           *auth.JWTManager
+	passwordManager      *auth.PasswordManager
+	totpManager          *auth.TOTPManager
+	webauthnManager      *auth.WebAuthnManager
+	loginThrottle        *loginThrottle
+	registration         *registrationPolicy
+	audit                *auditLog
+	mailer               mail.Mailer
+	frontendURL          string
+	emailVerificationTTL time.Duration
+	passwordResetTTL     time.Duration
+	mfaTokenTTL          time.Duration
+	magicLinkTTL         time.Duration
+	magicLinkURL         string
+	newDeviceAlerts      bool
+	secureAccountTTL     time.Duration
+	secureAccountURL     string
 }
 
 func NewAuthUsecase(
 	userRepo repositories.UserRepository,
+	sessionRepo repositories.SessionRepository,
+	tokenRepo repositories.OneTimeTokenRepository,
+	loginAttemptRepo repositories.LoginAttemptRepository,
+	auditRepo repositories.AuditEventRepository,
+	inviteRepo repositories.InviteRepository,
 	jwtManager *auth.JWTManager,
 	passwordManager *auth.PasswordManager,
-) AuthUsecase {
-	return &authUsecase{
-		userRepo:        userRepo,
-		jwtManager:      jwtManager,
-		passwordManager: passwordManager,
+	mailer mail.Mailer,
+	cfg *config.Config,
+) (AuthUsecase, error) {
+	emailVerificationTTL, err := time.ParseDuration(cfg.Auth.EmailVerificationExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email verification expiry: %w", err)
+	}
+
+	passwordResetTTL, err := time.ParseDuration(cfg.Auth.PasswordResetExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password reset expiry: %w", err)
+	}
+
+	mfaTokenTTL, err := time.ParseDuration(cfg.Auth.MFATokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mfa token expiry: %w", err)
+	}
+
+	magicLinkTTL, err := time.ParseDuration(cfg.Auth.MagicLinkExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid magic link expiry: %w", err)
 	}
+
+	secureAccountTTL, err := time.ParseDuration(cfg.Auth.SecureAccountExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secure account link expiry: %w", err)
+	}
+
+	throttle, err := newLoginThrottle(loginAttemptRepo, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	registration, err := newRegistrationPolicy(inviteRepo, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authUsecase{
+		userRepo:             userRepo,
+		sessionRepo:          sessionRepo,
+		tokenRepo:            tokenRepo,
+		jwtManager:           jwtManager,
+		passwordManager:      passwordManager,
+		totpManager:          auth.NewTOTPManager(cfg),
+		webauthnManager:      auth.NewWebAuthnManager(cfg),
+		loginThrottle:        throttle,
+		registration:         registration,
+		audit:                newAuditLog(auditRepo),
+		mailer:               mailer,
+		frontendURL:          strings.TrimRight(cfg.CORS.FrontendURL, "/"),
+		emailVerificationTTL: emailVerificationTTL,
+		passwordResetTTL:     passwordResetTTL,
+		mfaTokenTTL:          mfaTokenTTL,
+		magicLinkTTL:         magicLinkTTL,
+		magicLinkURL:         cfg.Auth.MagicLinkURL,
+		newDeviceAlerts:      cfg.Auth.NewDeviceAlerts,
+		secureAccountTTL:     secureAccountTTL,
+		secureAccountURL:     cfg.Auth.SecureAccountURL,
+	}, nil
 }
 
-func (u *authUsecase) Register(ctx context.Context, req *entities.RegisterRequest) (*entities.User, *auth.TokenPair, error) {
+func (u *authUsecase) Register(ctx context.Context, req *entities.RegisterRequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error) {
 	// Validate password
-	if err := u.passwordManager.ValidatePassword(req.Password); err != nil {
+	if err := u.passwordManager.ValidatePassword(req.Password, req.Email, req.FirstName, req.LastName); err != nil {
 		return nil, nil, fmt.Errorf("invalid password: %w", err)
 	}
 
+	// The policy goes first, so without an invite or allowed domain the
+	// response cannot tell whether an address is registered
+	invite, err := u.registration.admit(ctx, strings.ToLower(req.Email), req.InviteCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// An account an admin deactivated keeps its address
+	deactivated, err := u.userRepo.DeactivatedEmailExists(ctx, strings.ToLower(req.Email))
+	if err != nil {
+		u.registration.release(ctx, invite)
+		return nil, nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if deactivated {
+		u.registration.release(ctx, invite)
+		return nil, nil, ErrAccountDeactivated
+	}
+
 	// Check if email already exists
-	exists, err := u.userRepo.EmailExists(ctx, strings.ToLower(req.Email))
+	exists, err := emailTaken(ctx, u.userRepo, strings.ToLower(req.Email))
 	if err != nil {
+		u.registration.release(ctx, invite)
 		return nil, nil, fmt.Errorf("failed to check email existence: %w", err)
 	}
 	if exists {
+		u.registration.release(ctx, invite)
 		return nil, nil, fmt.Errorf("email already registered")
 	}
 
 	// Hash password
 	hashedPassword, err := u.passwordManager.HashPassword(req.Password)
 	if err != nil {
+		u.registration.release(ctx, invite)
 		return nil, nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
@@ -72,30 +278,83 @@ func (u *authUsecase) Register(ctx context.Context, req *entities.RegisterReques
 	}
 
 	if err := u.userRepo.Create(ctx, user); err != nil {
+		u.registration.release(ctx, invite)
 		return nil, nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// A failed email should not fail the registration; the user can ask for another one
+	if err := u.sendVerificationEmail(ctx, user); err != nil {
+		fmt.Printf("Failed to send verification email to user %s: %v\n", user.ID.Hex(), err)
+	}
+
 	// Generate tokens
-	tokens, err := u.jwtManager.GenerateTokenPair(user.ID.Hex(), user.Email)
+	tokens, err := u.startSession(ctx, user, client)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, nil, err
 	}
 
+	u.audit.record(ctx, client, &entities.AuditEvent{Type: entities.AuditRegister, UserID: user.ID.Hex(), Email: user.Email})
+
 	return user, tokens, nil
 }
 
-func (u *authUsecase) Login(ctx context.Context, req *entities.LoginRequest) (*entities.User, *auth.TokenPair, error) {
-	// Get user by email
+func (u *authUsecase) Login(ctx context.Context, req *entities.LoginRequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error) {
+	if err := u.loginThrottle.check(ctx, req.Email, client); err != nil {
+		return nil, nil, err
+	}
+
+	// Get user by email, including accounts deleted within the grace period
 	user, err := u.userRepo.GetByEmail(ctx, strings.ToLower(req.Email))
 	if err != nil {
+		user, err = u.userRepo.GetPendingDeletionByEmail(ctx, strings.ToLower(req.Email))
+	}
+	if err != nil {
+		u.loginThrottle.recordFailure(ctx, req.Email, client)
+		u.audit.record(ctx, client, &entities.AuditEvent{
+			Type:    entities.AuditLoginFailed,
+			Email:   req.Email,
+			Details: map[string]string{"method": "password", "reason": "unknown_email"},
+		})
 		return nil, nil, fmt.Errorf("invalid credentials")
 	}
 
 	// Verify password
 	if err := u.passwordManager.VerifyPassword(user.Password, req.Password); err != nil {
+		u.loginThrottle.recordFailure(ctx, req.Email, client)
+		u.audit.record(ctx, client, &entities.AuditEvent{
+			Type:    entities.AuditLoginFailed,
+			UserID:  user.ID.Hex(),
+			Email:   req.Email,
+			Details: map[string]string{"method": "password", "reason": "wrong_password"},
+		})
 		return nil, nil, fmt.Errorf("invalid credentials")
 	}
 
+	// Upgrade bcrypt or outdated Argon2id hashes while the plaintext is at hand
+	if u.passwordManager.NeedsRehash(user.Password) {
+		u.rehashPassword(ctx, user, req.Password)
+	}
+
+	// Someone else may know the password
+	if user.PasswordResetRequired {
+		return nil, nil, ErrPasswordResetRequired
+	}
+
+	// The password alone is not enough when a second factor is enrolled
+	if user.TwoFactorEnabled {
+		mfaToken, _, err := u.jwtManager.GenerateActionToken(user.ID.Hex(), user.Email, auth.MFAPendingTokenType, u.mfaTokenTTL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate mfa token: %w", err)
+		}
+		return nil, nil, &MFARequiredError{Token: mfaToken}
+	}
+
+	u.loginThrottle.recordSuccess(ctx, user.Email)
+
+	if err := u.restoreAccount(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
 	// Update last login
 	if err := u.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
 		// Log error but don't fail the login
@@ -103,68 +362,296 @@ func (u *authUsecase) Login(ctx context.Context, req *entities.LoginRequest) (*e
 	}
 
 	// Generate tokens
-	tokens, err := u.jwtManager.GenerateTokenPair(user.ID.Hex(), user.Email)
+	tokens, err := u.startSession(ctx, user, client)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, nil, err
 	}
 
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditLoginSucceeded,
+		UserID:  user.ID.Hex(),
+		Email:   user.Email,
+		Details: map[string]string{"method": "password"},
+	})
+
 	return user, tokens, nil
 }
 
-func (u *authUsecase) LoginWithGoogle(ctx context.Context, profile *entities.GoogleProfile) (*entities.User, *auth.TokenPair, error) {
-	user, err := u.userRepo.GetByEmail(ctx, strings.ToLower(profile.Email))
+// LoginWithProvider signs in the account linked to the provider identity. An
+// identity is only linked explicitly or when it creates a new account, so an
+// existing account with the same email is never taken over.
+func (u *authUsecase) LoginWithProvider(ctx context.Context, profile *entities.ExternalProfile, inviteCode string, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error) {
+	user, err := u.userRepo.GetByIdentity(ctx, profile.Provider, profile.Subject)
 	if err != nil {
-		user = &entities.User{
-			Email:        strings.ToLower(profile.Email),
-			AuthProvider: "google",
-			GoogleID:     profile.GoogleID,
-			FirstName:    profile.FirstName,
-			LastName:     profile.LastName,
-			Avatar:       profile.Avatar,
-			IsVerified:   true,
+		user, err = u.pendingDeletionByIdentity(ctx, profile)
+	}
+	if err != nil {
+		user, err = u.providerAccountByEmail(ctx, profile, inviteCode)
+		if err != nil {
+			return nil, nil, err
 		}
+	}
 
-		if err := u.userRepo.Create(ctx, user); err != nil {
-			return nil, nil, fmt.Errorf("failed to create google user: %w", err)
-		}
-	} else {
-		user.AuthProvider = "google"
-		user.GoogleID = profile.GoogleID
-		if profile.FirstName != "" {
-			user.FirstName = profile.FirstName
-		}
-		if profile.LastName != "" {
-			user.LastName = profile.LastName
-		}
-		if profile.Avatar != "" {
-			user.Avatar = profile.Avatar
-		}
-		user.IsVerified = true
+	if err := u.restoreAccount(ctx, user); err != nil {
+		return nil, nil, err
+	}
 
-		if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
-			return nil, nil, fmt.Errorf("failed to update google user: %w", err)
+	now := time.Now()
+	for i := range user.Identities {
+		if user.Identities[i].Provider == profile.Provider && user.Identities[i].Subject == profile.Subject {
+			user.Identities[i].LastUsedAt = &now
 		}
 	}
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, nil, fmt.Errorf("failed to update %s user: %w", profile.Provider, err)
+	}
 
 	if err := u.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
 		fmt.Printf("Failed to update last login for user %s: %v\n", user.ID.Hex(), err)
 	}
 
-	tokens, err := u.jwtManager.GenerateTokenPair(user.ID.Hex(), user.Email)
+	tokens, err := u.startSession(ctx, user, client)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, nil, err
 	}
 
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditProviderLogin,
+		UserID:  user.ID.Hex(),
+		Email:   profile.Email,
+		Details: map[string]string{"provider": profile.Provider},
+	})
+
 	return user, tokens, nil
 }
 
-func (u *authUsecase) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
-	accessToken, err := u.jwtManager.RefreshAccessToken(refreshToken)
+// providerAccountByEmail handles a provider identity that is not linked yet. It
+// creates a new account, adopts a Google ID stored before identities existed,
+// or reports that the email belongs to an account that has to link it first.
+func (u *authUsecase) providerAccountByEmail(ctx context.Context, profile *entities.ExternalProfile, inviteCode string) (*entities.User, error) {
+	if profile.Email == "" || !profile.EmailVerified {
+		return nil, fmt.Errorf("%s did not return a verified email address", profile.Provider)
+	}
+
+	identity := entities.LinkedIdentity{
+		Provider: profile.Provider,
+		Subject:  profile.Subject,
+		Email:    profile.Email,
+		LinkedAt: time.Now(),
+	}
+
+	// The address stays reserved while a deleted account can still be restored
+	if _, err := u.userRepo.GetPendingDeletionByEmail(ctx, strings.ToLower(profile.Email)); err == nil {
+		return nil, &LinkRequiredError{Provider: profile.Provider, Email: strings.ToLower(profile.Email)}
+	}
+
+	user, err := u.userRepo.GetByEmail(ctx, strings.ToLower(profile.Email))
+	if err == nil {
+		if profile.Provider == "google" && user.GoogleID != "" && user.GoogleID == profile.Subject {
+			user.Identities = append(user.Identities, identity)
+			return user, nil
+		}
+		return nil, &LinkRequiredError{Provider: profile.Provider, Email: user.Email}
+	}
+
+	deactivated, err := u.userRepo.DeactivatedEmailExists(ctx, strings.ToLower(profile.Email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if deactivated {
+		return nil, ErrAccountDeactivated
+	}
+
+	invite, err := u.registration.admit(ctx, strings.ToLower(profile.Email), inviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	user = &entities.User{
+		Email:        strings.ToLower(profile.Email),
+		AuthProvider: profile.Provider,
+		FirstName:    profile.FirstName,
+		LastName:     profile.LastName,
+		Avatar:       profile.Avatar,
+		IsVerified:   true,
+		Identities:   []entities.LinkedIdentity{identity},
+	}
+	if profile.Provider == "google" {
+		user.GoogleID = profile.Subject
+	}
+
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		u.registration.release(ctx, invite)
+		return nil, fmt.Errorf("failed to create %s user: %w", profile.Provider, err)
+	}
+
+	return user, nil
+}
+
+// BeginIdentityLink records that the provider login started with state is
+// meant to link an identity to the user rather than sign in.
+func (u *authUsecase) BeginIdentityLink(ctx context.Context, userID, state string) error {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.tokenRepo.Create(ctx, &entities.OneTimeToken{
+		UserID:    user.ID.Hex(),
+		Email:     user.Email,
+		Purpose:   entities.TokenPurposeIdentityLink,
+		TokenHash: auth.HashToken(state),
+		ExpiresAt: time.Now().Add(identityLinkTimeout),
+	}); err != nil {
+		return fmt.Errorf("failed to start linking: %w", err)
+	}
+
+	return nil
+}
+
+// FinishIdentityLink links the provider identity to the user who started the
+// link with state. It returns false when state belongs to a plain sign-in.
+func (u *authUsecase) FinishIdentityLink(ctx context.Context, state string, profile *entities.ExternalProfile) (bool, error) {
+	link, err := u.tokenRepo.GetByHash(ctx, auth.HashToken(state), entities.TokenPurposeIdentityLink)
+	if err != nil {
+		return false, nil
+	}
+	if !link.IsUsable() {
+		return true, fmt.Errorf("link request expired, please try again")
+	}
+	if err := u.tokenRepo.Consume(ctx, link.ID); err != nil {
+		return true, fmt.Errorf("link request expired, please try again")
+	}
+
+	user, err := u.GetProfile(ctx, link.UserID)
+	if err != nil {
+		return true, err
+	}
+
+	if owner, err := u.userRepo.GetByIdentity(ctx, profile.Provider, profile.Subject); err == nil {
+		if owner.ID == user.ID {
+			return true, nil
+		}
+		return true, fmt.Errorf("this %s account is already linked to another user", profile.Provider)
+	}
+	for _, identity := range user.Identities {
+		if identity.Provider == profile.Provider {
+			return true, fmt.Errorf("a %s account is already linked, unlink it first", profile.Provider)
+		}
+	}
+
+	user.Identities = append(user.Identities, entities.LinkedIdentity{
+		Provider: profile.Provider,
+		Subject:  profile.Subject,
+		Email:    profile.Email,
+		LinkedAt: time.Now(),
+	})
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return true, fmt.Errorf("failed to link %s account: %w", profile.Provider, err)
+	}
+
+	return true, nil
+}
+
+func (u *authUsecase) ListIdentities(ctx context.Context, userID string) ([]entities.LinkedIdentity, error) {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Identities == nil {
+		return []entities.LinkedIdentity{}, nil
+	}
+	return user.Identities, nil
+}
+
+func (u *authUsecase) UnlinkIdentity(ctx context.Context, userID, provider string) error {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]entities.LinkedIdentity, 0, len(user.Identities))
+	for _, identity := range user.Identities {
+		if identity.Provider != provider {
+			remaining = append(remaining, identity)
+		}
+	}
+	if len(remaining) == len(user.Identities) {
+		return fmt.Errorf("identity not found")
+	}
+	if countCredentials(user)-(len(user.Identities)-len(remaining)) < 1 {
+		return ErrLastCredential
+	}
+
+	user.Identities = remaining
+	if provider == "google" {
+		user.GoogleID = ""
+	}
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return fmt.Errorf("failed to unlink %s account: %w", provider, err)
+	}
+
+	return nil
+}
+
+func (u *authUsecase) RefreshToken(ctx context.Context, refreshToken string, client *entities.ClientInfo) (*auth.TokenPair, error) {
+	claims, err := u.jwtManager.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token: %w", err)
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token: missing session")
+	}
+
+	session, err := u.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if !session.IsActive() || session.UserID != claims.UserID {
+		return nil, fmt.Errorf("session is no longer active")
+	}
+
+	// A validly signed token that is not the current one has already been
+	// rotated, so someone is replaying it. Kill the whole session.
+	oldHash := auth.HashToken(refreshToken)
+	if session.RefreshTokenHash != oldHash {
+		u.revokeSession(ctx, session.ID)
+		return nil, ErrRefreshTokenReused
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	tokens, err := u.jwtManager.GenerateTokenPair(user.ID.Hex(), user.Email, user.EffectiveRole(), session.ID.Hex())
 	if err != nil {
-		return "", fmt.Errorf("failed to refresh token: %w", err)
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	// Rotation keeps the session's original expiry, so a session that is used
+	// constantly, or by someone replaying a stolen token first, still ends.
+	if err := u.sessionRepo.Rotate(ctx, session.ID, oldHash, auth.HashToken(tokens.RefreshToken), session.ExpiresAt); err != nil {
+		// Another request rotated the same token first.
+		u.revokeSession(ctx, session.ID)
+		return nil, ErrRefreshTokenReused
 	}
 
-	return accessToken, nil
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditTokenRefreshed,
+		UserID:  user.ID.Hex(),
+		Details: map[string]string{"session_id": session.ID.Hex()},
+	})
+
+	return tokens, nil
 }
 
 func (u *authUsecase) GetProfile(ctx context.Context, userID string) (*entities.User, error) {
@@ -181,7 +668,7 @@ func (u *authUsecase) GetProfile(ctx context.Context, userID string) (*entities.
 	return user, nil
 }
 
-func (u *authUsecase) UpdateProfile(ctx context.Context, userID string, req *entities.UpdateProfileRequest) (*entities.User, error) {
+func (u *authUsecase) UpdateProfile(ctx context.Context, userID string, req *entities.UpdateProfileRequest, client *entities.ClientInfo) (*entities.User, error) {
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID: %w", err)
@@ -194,42 +681,58 @@ func (u *authUsecase) UpdateProfile(ctx context.Context, userID string, req *ent
 	}
 
 	// Update only provided fields
+	var changed []string
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
+		changed = append(changed, "first_name")
 	}
 	if req.LastName != nil {
 		user.LastName = *req.LastName
+		changed = append(changed, "last_name")
 	}
 	if req.Avatar != nil {
 		user.Avatar = *req.Avatar
+		changed = append(changed, "avatar")
 	}
 	if req.Bio != nil {
 		user.Bio = *req.Bio
+		changed = append(changed, "bio")
 	}
 	if req.Phone != nil {
 		user.Phone = *req.Phone
+		changed = append(changed, "phone")
 	}
 	if req.Location != nil {
 		user.Location = *req.Location
+		changed = append(changed, "location")
 	}
 	if req.Website != nil {
 		user.Website = *req.Website
+		changed = append(changed, "website")
 	}
 	if req.LinkedIn != nil {
 		user.LinkedIn = *req.LinkedIn
+		changed = append(changed, "linkedin")
 	}
 	if req.GitHub != nil {
 		user.GitHub = *req.GitHub
+		changed = append(changed, "github")
 	}
 
 	if err := u.userRepo.Update(ctx, objectID, user); err != nil {
 		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditProfileUpdated,
+		UserID:  userID,
+		Details: map[string]string{"fields": strings.Join(changed, ",")},
+	})
+
 	return user, nil
 }
 
-func (u *authUsecase) ChangePassword(ctx context.Context, userID string, req *entities.ChangePasswordRequest) error {
+func (u *authUsecase) ChangePassword(ctx context.Context, userID string, req *entities.ChangePasswordRequest, client *entities.ClientInfo) error {
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return fmt.Errorf("invalid user ID: %w", err)
@@ -247,7 +750,7 @@ func (u *authUsecase) ChangePassword(ctx context.Context, userID string, req *en
 	}
 
 	// Validate new password
-	if err := u.passwordManager.ValidatePassword(req.NewPassword); err != nil {
+	if err := u.passwordManager.ValidatePassword(req.NewPassword, user.Email, user.FirstName, user.LastName); err != nil {
 		return fmt.Errorf("invalid new password: %w", err)
 	}
 
@@ -259,20 +762,1170 @@ func (u *authUsecase) ChangePassword(ctx context.Context, userID string, req *en
 
 	// Update password
 	user.Password = hashedPassword
+	user.PasswordResetRequired = false
 	if err := u.userRepo.Update(ctx, objectID, user); err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditPasswordChanged,
+		UserID:  userID,
+		Details: map[string]string{"method": "change"},
+	})
+
 	return nil
 }
 
-func (u *authUsecase) Logout(ctx context.Context, userID string) error {
-	// In a more sophisticated implementation, you might:
-	// 1. Blacklist the refresh token
-	// 2. Store logout timestamp
-	// 3. Invalidate all sessions for the user
-	
-	// For now, we'll just return success
-	// The client should remove the tokens
+func (u *authUsecase) Logout(ctx context.Context, userID, sessionID string, client *entities.ClientInfo) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID: %w", err)
+	}
+
+	session, err := u.sessionRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.UserID != userID {
+		return fmt.Errorf("session belongs to different user")
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	if err := u.sessionRepo.Revoke(ctx, objectID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditLogout,
+		UserID:  userID,
+		Details: map[string]string{"session_id": sessionID},
+	})
+
+	return nil
+}
+
+func (u *authUsecase) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*entities.SessionResponse, error) {
+	sessions, err := u.sessionRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	responses := make([]*entities.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, session.ToResponse(currentSessionID))
+	}
+
+	return responses, nil
+}
+
+func (u *authUsecase) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID: %w", err)
+	}
+
+	session, err := u.sessionRepo.GetByID(ctx, objectID)
+	if err != nil || session.UserID != userID || !session.IsActive() {
+		return fmt.Errorf("session not found")
+	}
+
+	if err := u.sessionRepo.Revoke(ctx, objectID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (u *authUsecase) RevokeAllSessions(ctx context.Context, userID string) error {
+	if err := u.sessionRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (u *authUsecase) VerifyEmail(ctx context.Context, token string) (*entities.User, error) {
+	claims, err := u.jwtManager.ValidateActionToken(token, auth.EmailVerificationTokenType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+
+	// Only the most recently issued token counts, and only once
+	if user.VerificationTokenID == "" || user.VerificationTokenID != claims.ID || user.Email != claims.Email {
+		return nil, fmt.Errorf("verification token has already been used or replaced")
+	}
+
+	user.IsVerified = true
+	user.VerificationTokenID = ""
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return user, nil
+}
+
+func (u *authUsecase) ResendVerification(ctx context.Context, userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := u.userRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.IsVerified {
+		return fmt.Errorf("email is already verified")
+	}
+	if user.VerificationSentAt != nil && time.Since(*user.VerificationSentAt) < verificationResendCooldown {
+		return fmt.Errorf("verification email was sent recently, please try again later")
+	}
+
+	if err := u.sendVerificationEmail(ctx, user); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
 	return nil
 }
+
+func (u *authUsecase) ForgotPassword(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetByEmail(ctx, strings.ToLower(email))
+	if err != nil {
+		// Unknown addresses look exactly like known ones to the caller
+		return nil
+	}
+
+	return u.sendPasswordReset(ctx, user, "Someone asked to reset the password for your DevFolio account.")
+}
+
+// sendPasswordReset mails a new reset link, which replaces any earlier one.
+// intro is the opening sentence explaining why the email was sent.
+func (u *authUsecase) sendPasswordReset(ctx context.Context, user *entities.User, intro string) error {
+	// Only the latest reset link should work
+	if err := u.tokenRepo.DeleteByUserID(ctx, user.ID.Hex(), entities.TokenPurposePasswordReset); err != nil {
+		return fmt.Errorf("failed to clear previous reset tokens: %w", err)
+	}
+
+	token, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	resetToken := &entities.OneTimeToken{
+		UserID:    user.ID.Hex(),
+		Email:     user.Email,
+		Purpose:   entities.TokenPurposePasswordReset,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(u.passwordResetTTL),
+	}
+	if err := u.tokenRepo.Create(ctx, resetToken); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	// Send in the background so the response time does not reveal whether the account exists
+	link := u.frontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg := &mail.Message{
+		To:      user.Email,
+		Subject: "Reset your DevFolio password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n%s Open the link below to choose a new password:\n\n%s\n\nThe link expires in %s and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			user.FirstName, intro, link, u.passwordResetTTL,
+		),
+	}
+	go func() {
+		if err := u.mailer.Send(context.Background(), msg); err != nil {
+			fmt.Printf("Failed to send password reset email to user %s: %v\n", user.ID.Hex(), err)
+		}
+	}()
+
+	return nil
+}
+
+func (u *authUsecase) ResetPassword(ctx context.Context, req *entities.ResetPasswordRequest, client *entities.ClientInfo) error {
+	resetToken, err := u.tokenRepo.GetByHash(ctx, auth.HashToken(req.Token), entities.TokenPurposePasswordReset)
+	if err != nil || !resetToken.IsUsable() {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(resetToken.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	// Validate before consuming so a rejected password does not burn the link
+	if err := u.passwordManager.ValidatePassword(req.NewPassword, user.Email, user.FirstName, user.LastName); err != nil {
+		return fmt.Errorf("invalid new password: %w", err)
+	}
+
+	if err := u.tokenRepo.Consume(ctx, resetToken.ID); err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	hashedPassword, err := u.passwordManager.HashPassword(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash new password: %w", err)
+	}
+
+	user.Password = hashedPassword
+	user.PasswordResetRequired = false
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditPasswordChanged,
+		UserID:  user.ID.Hex(),
+		Details: map[string]string{"method": "reset"},
+	})
+
+	// Whoever knew the old password must not stay signed in
+	if err := u.sessionRepo.RevokeAllByUserID(ctx, user.ID.Hex()); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (u *authUsecase) RequestMagicLink(ctx context.Context, req *entities.MagicLinkRequest, client *entities.ClientInfo) error {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// The IP limit goes first, so one client cannot use up the cooldown of many addresses
+	if client != nil && client.IPAddress != "" {
+		if err := u.loginThrottle.limitRequests(ctx, "magic_link:ip:"+client.IPAddress, magicLinkIPLimit, magicLinkIPWindow,
+			"too many sign-in links were requested from your network, try again later"); err != nil {
+			return err
+		}
+	}
+	if err := u.loginThrottle.limitRequests(ctx, "magic_link:email:"+entities.CanonicalEmail(email), 1, magicLinkCooldown,
+		"a sign-in link was sent to this address recently, please try again later"); err != nil {
+		return err
+	}
+
+	// The link is signed so it cannot be forged, and stored so it works only once
+	token, _, err := u.jwtManager.GenerateActionToken("", email, auth.MagicLinkTokenType, u.magicLinkTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate magic link: %w", err)
+	}
+
+	magicToken := &entities.OneTimeToken{
+		Email:     email,
+		Purpose:   entities.TokenPurposeMagicLink,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(u.magicLinkTTL),
+	}
+	if err := u.tokenRepo.Create(ctx, magicToken); err != nil {
+		return fmt.Errorf("failed to store magic link: %w", err)
+	}
+
+	// Send in the background so the response time does not reveal whether the account exists
+	link := u.magicLinkURL + "?token=" + url.QueryEscape(token)
+	// The invite travels with the link, in case the address has no account yet
+	if req.InviteCode != "" {
+		link += "&invite_code=" + url.QueryEscape(req.InviteCode)
+	}
+	msg := &mail.Message{
+		To:      email,
+		Subject: "Your DevFolio sign-in link",
+		Body: fmt.Sprintf(
+			"Hi,\n\nOpen the link below to sign in to DevFolio. If you do not have an account yet, one will be created for this address:\n\n%s\n\nThe link expires in %s and can only be used once. If you did not ask for it, you can ignore this email.\n",
+			link, u.magicLinkTTL,
+		),
+	}
+	go func() {
+		if err := u.mailer.Send(context.Background(), msg); err != nil {
+			fmt.Printf("Failed to send magic link: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// LoginWithMagicLink signs in the owner of the address the link was sent to,
+// creating the account first if there is none. Opening the link proves the
+// address, so it is marked verified. Enrolled second factors are still required.
+func (u *authUsecase) LoginWithMagicLink(ctx context.Context, token, inviteCode string, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error) {
+	claims, err := u.jwtManager.ValidateActionToken(token, auth.MagicLinkTokenType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid or expired sign-in link")
+	}
+
+	magicToken, err := u.tokenRepo.GetByHash(ctx, auth.HashToken(token), entities.TokenPurposeMagicLink)
+	if err != nil || !magicToken.IsUsable() || magicToken.Email != claims.Email {
+		return nil, nil, fmt.Errorf("invalid or expired sign-in link")
+	}
+	if err := u.tokenRepo.Consume(ctx, magicToken.ID); err != nil {
+		return nil, nil, fmt.Errorf("invalid or expired sign-in link")
+	}
+
+	user, err := u.userRepo.GetByEmail(ctx, magicToken.Email)
+	if err != nil {
+		user, err = u.userRepo.GetPendingDeletionByEmail(ctx, magicToken.Email)
+	}
+	created := false
+	if err != nil {
+		deactivated, err := u.userRepo.DeactivatedEmailExists(ctx, magicToken.Email)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check email: %w", err)
+		}
+		if deactivated {
+			return nil, nil, ErrAccountDeactivated
+		}
+
+		invite, err := u.registration.admit(ctx, magicToken.Email, inviteCode)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		user = &entities.User{
+			Email:        magicToken.Email,
+			AuthProvider: "magic_link",
+			IsVerified:   true,
+		}
+		if err := u.userRepo.Create(ctx, user); err != nil {
+			u.registration.release(ctx, invite)
+			return nil, nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		created = true
+	}
+
+	if user.TwoFactorEnabled {
+		mfaToken, _, err := u.jwtManager.GenerateActionToken(user.ID.Hex(), user.Email, auth.MFAPendingTokenType, u.mfaTokenTTL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate mfa token: %w", err)
+		}
+		return nil, nil, &MFARequiredError{Token: mfaToken}
+	}
+
+	if err := u.restoreAccount(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	if !user.IsVerified {
+		user.IsVerified = true
+		user.VerificationTokenID = ""
+		if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+			fmt.Printf("Failed to mark email verified for user %s: %v\n", user.ID.Hex(), err)
+		}
+	}
+
+	if err := u.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		fmt.Printf("Failed to update last login for user %s: %v\n", user.ID.Hex(), err)
+	}
+
+	tokens, err := u.startSession(ctx, user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eventType := entities.AuditLoginSucceeded
+	if created {
+		eventType = entities.AuditRegister
+	}
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    eventType,
+		UserID:  user.ID.Hex(),
+		Email:   user.Email,
+		Details: map[string]string{"method": "magic_link"},
+	})
+
+	return user, tokens, nil
+}
+
+func (u *authUsecase) LoginMFA(ctx context.Context, req *entities.LoginMFARequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error) {
+	claims, err := u.jwtManager.ValidateActionToken(req.MFAToken, auth.MFAPendingTokenType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid or expired mfa token")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid or expired mfa token")
+	}
+
+	user, err := u.userRepo.GetByIDIncludingInactive(ctx, userID)
+	if err != nil || !user.TwoFactorEnabled || (!user.IsActive && !user.PendingDeletion()) {
+		return nil, nil, fmt.Errorf("invalid or expired mfa token")
+	}
+
+	// Codes are throttled together with passwords, so a leaked password does
+	// not buy unlimited guesses at the second factor
+	if err := u.loginThrottle.check(ctx, user.Email, client); err != nil {
+		return nil, nil, err
+	}
+
+	if err := u.verifySecondFactor(ctx, user, req.Code); err != nil {
+		u.loginThrottle.recordFailure(ctx, user.Email, client)
+		u.audit.record(ctx, client, &entities.AuditEvent{
+			Type:    entities.AuditLoginFailed,
+			UserID:  user.ID.Hex(),
+			Email:   user.Email,
+			Details: map[string]string{"reason": "wrong_second_factor"},
+		})
+		return nil, nil, err
+	}
+
+	u.loginThrottle.recordSuccess(ctx, user.Email)
+
+	if err := u.restoreAccount(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	if err := u.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		fmt.Printf("Failed to update last login for user %s: %v\n", user.ID.Hex(), err)
+	}
+
+	tokens, err := u.startSession(ctx, user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditLoginSucceeded,
+		UserID:  user.ID.Hex(),
+		Email:   user.Email,
+		Details: map[string]string{"second_factor": "true"},
+	})
+
+	return user, tokens, nil
+}
+
+func (u *authUsecase) EnrollTwoFactor(ctx context.Context, userID string) (*entities.TwoFactorSetupResponse, error) {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	secret, err := u.totpManager.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	// Not enabled until the user proves their authenticator works
+	user.TOTPSecret = secret
+	user.TOTPLastUsedStep = 0
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, fmt.Errorf("failed to store totp secret: %w", err)
+	}
+
+	return &entities.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: u.totpManager.ProvisioningURI(secret, user.Email),
+	}, nil
+}
+
+func (u *authUsecase) ConfirmTwoFactor(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, fmt.Errorf("start two-factor enrollment first")
+	}
+
+	step, ok := u.totpManager.Validate(user.TOTPSecret, code, user.TOTPLastUsedStep)
+	if !ok {
+		return nil, fmt.Errorf("invalid authentication code")
+	}
+
+	recoveryCodes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, recoveryCode := range recoveryCodes {
+		hashes = append(hashes, auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	user.TwoFactorEnabled = true
+	user.TOTPLastUsedStep = step
+	user.RecoveryCodeHashes = hashes
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	return recoveryCodes, nil
+}
+
+func (u *authUsecase) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled {
+		return fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	if err := u.verifySecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+
+	user.TwoFactorEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastUsedStep = 0
+	user.RecoveryCodeHashes = nil
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	return nil
+}
+
+func (u *authUsecase) BeginPasskeyRegistration(ctx context.Context, userID string) (*entities.PasskeyCreationOptions, error) {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := u.startPasskeyCeremony(ctx, user, entities.TokenPurposePasskeyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.webauthnManager.CreationOptions(user, challenge), nil
+}
+
+func (u *authUsecase) FinishPasskeyRegistration(ctx context.Context, userID string, req *entities.PasskeyRegistrationRequest) (*entities.PasskeyCredential, error) {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := u.webauthnManager.ClientDataChallenge(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passkey response: %w", err)
+	}
+
+	ceremony, err := u.finishPasskeyCeremony(ctx, challenge, entities.TokenPurposePasskeyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserID != user.ID.Hex() {
+		return nil, fmt.Errorf("passkey ceremony belongs to different user")
+	}
+
+	credential, err := u.webauthnManager.VerifyRegistration(&req.Credential.Response, challenge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify passkey: %w", err)
+	}
+
+	for _, passkey := range user.Passkeys {
+		if passkey.ID == credential.ID {
+			return nil, fmt.Errorf("passkey is already registered")
+		}
+	}
+
+	credential.Name = strings.TrimSpace(req.Name)
+	if credential.Name == "" {
+		credential.Name = "Passkey"
+	}
+	credential.CreatedAt = time.Now()
+
+	user.Passkeys = append(user.Passkeys, *credential)
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, fmt.Errorf("failed to store passkey: %w", err)
+	}
+
+	return credential, nil
+}
+
+func (u *authUsecase) BeginPasskeyLogin(ctx context.Context, client *entities.ClientInfo) (*entities.PasskeyRequestOptions, error) {
+	if client != nil && client.IPAddress != "" {
+		if err := u.loginThrottle.limitRequests(ctx, "passkey_login:ip:"+client.IPAddress, passkeyLoginIPLimit, passkeyLoginIPWindow,
+			"too many passkey sign-ins were started from your network, try again later"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Discoverable login: the user is not known until the authenticator answers
+	challenge, err := u.startPasskeyCeremony(ctx, nil, entities.TokenPurposePasskeyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.webauthnManager.RequestOptions(challenge), nil
+}
+
+func (u *authUsecase) FinishPasskeyLogin(ctx context.Context, req *entities.PasskeyLoginRequest, client *entities.ClientInfo) (*entities.User, *auth.TokenPair, error) {
+	challenge, err := u.webauthnManager.ClientDataChallenge(req.Response.ClientDataJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid passkey response: %w", err)
+	}
+
+	if _, err := u.finishPasskeyCeremony(ctx, challenge, entities.TokenPurposePasskeyLogin); err != nil {
+		return nil, nil, err
+	}
+
+	userID, err := auth.ParsePasskeyUserHandle(req.Response.UserHandle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("passkey not recognized")
+	}
+
+	user, err := u.userRepo.GetByIDIncludingInactive(ctx, userID)
+	if err != nil || (!user.IsActive && !user.PendingDeletion()) {
+		return nil, nil, fmt.Errorf("passkey not recognized")
+	}
+
+	credentialID := strings.TrimRight(req.ID, "=")
+	index := -1
+	for i, passkey := range user.Passkeys {
+		if passkey.ID == credentialID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, nil, fmt.Errorf("passkey not recognized")
+	}
+
+	signCount, err := u.webauthnManager.VerifyAssertion(&req.Response, challenge, &user.Passkeys[index])
+	if err != nil {
+		u.audit.record(ctx, client, &entities.AuditEvent{
+			Type:    entities.AuditLoginFailed,
+			UserID:  user.ID.Hex(),
+			Email:   user.Email,
+			Details: map[string]string{"method": "passkey", "reason": "invalid_assertion"},
+		})
+		return nil, nil, fmt.Errorf("failed to verify passkey: %w", err)
+	}
+
+	if err := u.restoreAccount(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	user.Passkeys[index].SignCount = signCount
+	user.Passkeys[index].LastUsedAt = &now
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return nil, nil, fmt.Errorf("failed to update passkey: %w", err)
+	}
+
+	if err := u.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		fmt.Printf("Failed to update last login for user %s: %v\n", user.ID.Hex(), err)
+	}
+
+	tokens, err := u.startSession(ctx, user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditLoginSucceeded,
+		UserID:  user.ID.Hex(),
+		Email:   user.Email,
+		Details: map[string]string{"method": "passkey"},
+	})
+
+	return user, tokens, nil
+}
+
+func (u *authUsecase) ListPasskeys(ctx context.Context, userID string) ([]entities.PasskeyCredential, error) {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Passkeys == nil {
+		return []entities.PasskeyCredential{}, nil
+	}
+	return user.Passkeys, nil
+}
+
+func (u *authUsecase) DeletePasskey(ctx context.Context, userID, credentialID string) error {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]entities.PasskeyCredential, 0, len(user.Passkeys))
+	for _, passkey := range user.Passkeys {
+		if passkey.ID != credentialID {
+			remaining = append(remaining, passkey)
+		}
+	}
+	if len(remaining) == len(user.Passkeys) {
+		return fmt.Errorf("passkey not found")
+	}
+	if countCredentials(user) <= 1 {
+		return ErrLastCredential
+	}
+
+	user.Passkeys = remaining
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+
+	return nil
+}
+
+// Reauthenticate checks that the signed-in user is really present before a
+// sensitive change: by password when the account has one, otherwise by a
+// sign-in within the last few minutes, plus a second factor when enrolled.
+func (u *authUsecase) Reauthenticate(ctx context.Context, userID, sessionID string, req *entities.ReauthenticateRequest, client *entities.ClientInfo) (*entities.User, error) {
+	user, err := u.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.loginThrottle.check(ctx, user.Email, client); err != nil {
+		return nil, err
+	}
+
+	if user.Password != "" {
+		if req.Password == "" {
+			return nil, ErrReauthenticationRequired
+		}
+		if err := u.passwordManager.VerifyPassword(user.Password, req.Password); err != nil {
+			u.loginThrottle.recordFailure(ctx, user.Email, client)
+			return nil, fmt.Errorf("password is incorrect")
+		}
+	} else if !u.sessionIsFresh(ctx, userID, sessionID) {
+		return nil, ErrReauthenticationRequired
+	}
+
+	if user.TwoFactorEnabled {
+		if req.Code == "" {
+			return nil, ErrReauthenticationRequired
+		}
+		if err := u.verifySecondFactor(ctx, user, req.Code); err != nil {
+			u.loginThrottle.recordFailure(ctx, user.Email, client)
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (u *authUsecase) sessionIsFresh(ctx context.Context, userID, sessionID string) bool {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false
+	}
+
+	session, err := u.sessionRepo.GetByID(ctx, objectID)
+	return err == nil && session.UserID == userID && session.IsActive() && time.Since(session.CreatedAt) <= reauthWindow
+}
+
+// emailTaken reports whether the address belongs to an active account, to a
+// deleted one that can still be restored or to one an admin deactivated.
+func emailTaken(ctx context.Context, userRepo repositories.UserRepository, email string) (bool, error) {
+	exists, err := userRepo.EmailExists(ctx, email)
+	if err != nil || exists {
+		return exists, err
+	}
+
+	if _, err := userRepo.GetPendingDeletionByEmail(ctx, email); err == nil {
+		return true, nil
+	}
+
+	return userRepo.DeactivatedEmailExists(ctx, email)
+}
+
+// pendingDeletionByIdentity finds a deleted account, still in its grace
+// period, that the provider identity is linked to.
+func (u *authUsecase) pendingDeletionByIdentity(ctx context.Context, profile *entities.ExternalProfile) (*entities.User, error) {
+	if profile.Email == "" {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	user, err := u.userRepo.GetPendingDeletionByEmail(ctx, strings.ToLower(profile.Email))
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range user.Identities {
+		if identity.Provider == profile.Provider && identity.Subject == profile.Subject {
+			return user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("user not found")
+}
+
+// restoreAccount cancels a pending account deletion when its owner signs in
+// during the grace period. Portfolios stay unpublished until the user
+// publishes them again.
+func (u *authUsecase) restoreAccount(ctx context.Context, user *entities.User) error {
+	if user.IsActive {
+		return nil
+	}
+	if !user.PendingDeletion() {
+		return ErrAccountDeactivated
+	}
+
+	if err := u.userRepo.SetActive(ctx, user.ID, true); err != nil {
+		return fmt.Errorf("failed to restore account: %w", err)
+	}
+	user.IsActive = true
+	user.DeletionScheduledAt = nil
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return fmt.Errorf("failed to restore account: %w", err)
+	}
+
+	return nil
+}
+
+// countCredentials counts the independent ways the user can sign in: a
+// password, each passkey and each linked provider.
+func countCredentials(user *entities.User) int {
+	count := len(user.Passkeys) + len(user.Identities)
+	if user.Password != "" {
+		count++
+	}
+
+	// Google accounts created before identities existed only have a GoogleID
+	if user.GoogleID != "" {
+		linked := false
+		for _, identity := range user.Identities {
+			linked = linked || identity.Provider == "google"
+		}
+		if !linked {
+			count++
+		}
+	}
+
+	return count
+}
+
+// startPasskeyCeremony stores a fresh WebAuthn challenge so the matching finish
+// call can be checked and the challenge used only once.
+func (u *authUsecase) startPasskeyCeremony(ctx context.Context, user *entities.User, purpose string) (string, error) {
+	challenge, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate passkey challenge: %w", err)
+	}
+
+	ceremony := &entities.OneTimeToken{
+		Purpose:   purpose,
+		TokenHash: auth.HashToken(challenge),
+		ExpiresAt: time.Now().Add(auth.PasskeyCeremonyTimeout),
+	}
+	if user != nil {
+		ceremony.UserID = user.ID.Hex()
+		ceremony.Email = user.Email
+	}
+
+	if err := u.tokenRepo.Create(ctx, ceremony); err != nil {
+		return "", fmt.Errorf("failed to store passkey challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+func (u *authUsecase) finishPasskeyCeremony(ctx context.Context, challenge, purpose string) (*entities.OneTimeToken, error) {
+	ceremony, err := u.tokenRepo.GetByHash(ctx, auth.HashToken(challenge), purpose)
+	if err != nil || !ceremony.IsUsable() {
+		return nil, fmt.Errorf("passkey challenge is invalid or expired")
+	}
+
+	if err := u.tokenRepo.Consume(ctx, ceremony.ID); err != nil {
+		return nil, fmt.Errorf("passkey challenge is invalid or expired")
+	}
+
+	return ceremony, nil
+}
+
+// rehashPassword replaces the user's stored hash with one made with the current
+// parameters. Failures are only logged since the login itself succeeded.
+func (u *authUsecase) rehashPassword(ctx context.Context, user *entities.User, password string) {
+	hashedPassword, err := u.passwordManager.HashPassword(password)
+	if err != nil {
+		fmt.Printf("Failed to rehash password for user %s: %v\n", user.ID.Hex(), err)
+		return
+	}
+
+	user.Password = hashedPassword
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		fmt.Printf("Failed to store rehashed password for user %s: %v\n", user.ID.Hex(), err)
+	}
+}
+
+// verifySecondFactor accepts either a current TOTP code or one of the user's
+// unused recovery codes, and records the use so neither can be replayed.
+func (u *authUsecase) verifySecondFactor(ctx context.Context, user *entities.User, code string) error {
+	if step, ok := u.totpManager.Validate(user.TOTPSecret, code, user.TOTPLastUsedStep); ok {
+		user.TOTPLastUsedStep = step
+		if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+			return fmt.Errorf("failed to record authentication code: %w", err)
+		}
+		return nil
+	}
+
+	hash := auth.HashToken(auth.NormalizeRecoveryCode(code))
+	for i, stored := range user.RecoveryCodeHashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) != 1 {
+			continue
+		}
+
+		remaining := make([]string, 0, len(user.RecoveryCodeHashes)-1)
+		remaining = append(remaining, user.RecoveryCodeHashes[:i]...)
+		remaining = append(remaining, user.RecoveryCodeHashes[i+1:]...)
+		user.RecoveryCodeHashes = remaining
+		if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+			return fmt.Errorf("failed to record recovery code use: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("invalid authentication code")
+}
+
+// sendVerificationEmail issues a new verification token, which replaces any earlier one, and mails it.
+func (u *authUsecase) sendVerificationEmail(ctx context.Context, user *entities.User) error {
+	token, tokenID, err := u.jwtManager.GenerateActionToken(user.ID.Hex(), user.Email, auth.EmailVerificationTokenType, u.emailVerificationTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	now := time.Now()
+	user.VerificationTokenID = tokenID
+	user.VerificationSentAt = &now
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err)
+	}
+
+	link := u.frontendURL + "/verify-email?token=" + url.QueryEscape(token)
+	return u.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Verify your DevFolio email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not create a DevFolio account, you can ignore this email.\n",
+			user.FirstName, link, u.emailVerificationTTL,
+		),
+	})
+}
+
+// startSession creates a new refresh token family for the user and issues its first token pair.
+func (u *authUsecase) startSession(ctx context.Context, user *entities.User, client *entities.ClientInfo) (*auth.TokenPair, error) {
+	session := &entities.Session{
+		UserID:    user.ID.Hex(),
+		ExpiresAt: time.Now().Add(u.jwtManager.GetRefreshTokenTTL()),
+	}
+	if client != nil {
+		session.IPAddress = client.IPAddress
+		session.UserAgent = client.UserAgent
+	}
+
+	if err := u.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	tokens, err := u.jwtManager.GenerateTokenPair(user.ID.Hex(), user.Email, user.EffectiveRole(), session.ID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	if err := u.sessionRepo.Rotate(ctx, session.ID, "", auth.HashToken(tokens.RefreshToken), session.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to bind session token: %w", err)
+	}
+
+	u.recognizeDevice(ctx, user, client)
+
+	return tokens, nil
+}
+
+// recognizeDevice adds the client to the user's known devices. A sign-in from a
+// device or network that is not among them is reported to the user, except on
+// the first sign-in, when there is nothing to compare with.
+func (u *authUsecase) recognizeDevice(ctx context.Context, user *entities.User, client *entities.ClientInfo) {
+	if client == nil {
+		return
+	}
+
+	now := time.Now()
+	fingerprint := deviceFingerprint(client.UserAgent)
+	network := networkPrefix(client.IPAddress)
+	firstSignIn := len(user.KnownDevices) == 0
+
+	devices := user.KnownDevices
+	knownFingerprint := false
+	for i := range devices {
+		if devices[i].Fingerprint != fingerprint {
+			continue
+		}
+		knownFingerprint = true
+		if devices[i].Network == network {
+			devices[i].IPAddress = client.IPAddress
+			devices[i].UserAgent = client.UserAgent
+			devices[i].LastSeenAt = now
+			u.saveKnownDevices(ctx, user, devices)
+			return
+		}
+	}
+
+	device := entities.KnownDevice{
+		ID:          primitive.NewObjectID(),
+		Fingerprint: fingerprint,
+		Network:     network,
+		UserAgent:   client.UserAgent,
+		IPAddress:   client.IPAddress,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	if len(devices) >= maxKnownDevices {
+		sort.Slice(devices, func(i, j int) bool { return devices[i].LastSeenAt.After(devices[j].LastSeenAt) })
+		devices = devices[:maxKnownDevices-1]
+	}
+	u.saveKnownDevices(ctx, user, append(devices, device))
+
+	if firstSignIn {
+		return
+	}
+
+	reason := "new_network"
+	if !knownFingerprint {
+		reason = "new_device"
+	}
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditNewDeviceLogin,
+		UserID:  user.ID.Hex(),
+		Email:   user.Email,
+		Details: map[string]string{"reason": reason, "network": network},
+	})
+
+	if u.newDeviceAlerts {
+		if err := u.sendNewDeviceAlert(ctx, user, &device, reason); err != nil {
+			fmt.Printf("Failed to send new device alert to user %s: %v\n", user.ID.Hex(), err)
+		}
+	}
+}
+
+func (u *authUsecase) saveKnownDevices(ctx context.Context, user *entities.User, devices []entities.KnownDevice) {
+	user.KnownDevices = devices
+	if err := u.userRepo.UpdateKnownDevices(ctx, user.ID, devices); err != nil {
+		fmt.Printf("Failed to update known devices for user %s: %v\n", user.ID.Hex(), err)
+	}
+}
+
+// sendNewDeviceAlert tells the user about the sign-in, with a link to lock the
+// account if it was not them.
+func (u *authUsecase) sendNewDeviceAlert(ctx context.Context, user *entities.User, device *entities.KnownDevice, reason string) error {
+	token, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate secure account token: %w", err)
+	}
+
+	secureToken := &entities.OneTimeToken{
+		UserID:    user.ID.Hex(),
+		Email:     user.Email,
+		Purpose:   entities.TokenPurposeSecureAccount,
+		TokenHash: auth.HashToken(token),
+		DeviceID:  device.ID.Hex(),
+		ExpiresAt: time.Now().Add(u.secureAccountTTL),
+	}
+	if err := u.tokenRepo.Create(ctx, secureToken); err != nil {
+		return fmt.Errorf("failed to store secure account token: %w", err)
+	}
+
+	source := "a new device"
+	if reason == "new_network" {
+		source = "a new network"
+	}
+	link := u.secureAccountURL + "?token=" + url.QueryEscape(token)
+	msg := &mail.Message{
+		To:      user.Email,
+		Subject: "New sign-in to your DevFolio account",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour DevFolio account was just signed in to from %s.\n\nDevice: %s\nIP address: %s\nTime: %s\n\nIf this was you, there is nothing to do. If it wasn't, open the link below. It signs you out everywhere, and you will have to reset your password before it can be used again:\n\n%s\n\nThe link expires in %s.\n",
+			user.FirstName, source, device.UserAgent, device.IPAddress, device.LastSeenAt.UTC().Format(time.RFC1123), link, u.secureAccountTTL,
+		),
+	}
+	go func() {
+		if err := u.mailer.Send(context.Background(), msg); err != nil {
+			fmt.Printf("Failed to send new device alert to user %s: %v\n", user.ID.Hex(), err)
+		}
+	}()
+
+	return nil
+}
+
+// SecureAccount signs the user out of every session, forgets the reported
+// device and, for accounts with a password, blocks the password until it is
+// reset and mails a reset link.
+func (u *authUsecase) SecureAccount(ctx context.Context, token string, client *entities.ClientInfo) error {
+	secureToken, err := u.tokenRepo.GetByHash(ctx, auth.HashToken(token), entities.TokenPurposeSecureAccount)
+	if err != nil || !secureToken.IsUsable() {
+		return fmt.Errorf("invalid or expired link")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(secureToken.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid or expired link")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("invalid or expired link")
+	}
+
+	if err := u.tokenRepo.Consume(ctx, secureToken.ID); err != nil {
+		return fmt.Errorf("invalid or expired link")
+	}
+
+	if err := u.sessionRepo.RevokeAllByUserID(ctx, user.ID.Hex()); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	devices := make([]entities.KnownDevice, 0, len(user.KnownDevices))
+	for _, device := range user.KnownDevices {
+		if device.ID.Hex() != secureToken.DeviceID {
+			devices = append(devices, device)
+		}
+	}
+	user.KnownDevices = devices
+	user.PasswordResetRequired = user.Password != ""
+	if err := u.userRepo.Update(ctx, user.ID, user); err != nil {
+		return fmt.Errorf("failed to secure account: %w", err)
+	}
+
+	u.audit.record(ctx, client, &entities.AuditEvent{
+		Type:    entities.AuditAccountSecured,
+		UserID:  user.ID.Hex(),
+		Email:   user.Email,
+		Details: map[string]string{"device_id": secureToken.DeviceID},
+	})
+
+	if user.PasswordResetRequired {
+		if err := u.sendPasswordReset(ctx, user, "You reported a sign-in to your DevFolio account that was not you, so your password has to be reset before it can be used again."); err != nil {
+			fmt.Printf("Failed to send password reset email to user %s: %v\n", user.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+func (u *authUsecase) revokeSession(ctx context.Context, sessionID primitive.ObjectID) {
+	if err := u.sessionRepo.Revoke(ctx, sessionID); err != nil {
+		fmt.Printf("Failed to revoke session %s: %v\n", sessionID.Hex(), err)
+	}
+}
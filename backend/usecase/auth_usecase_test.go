@@ -0,0 +1,500 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/auth/webauthntest"
+	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/mail"
+	"devfolio-backend/repositories"
+)
+
+const (
+	testRPID   = "devfolio.test"
+	testOrigin = "https://devfolio.test"
+)
+
+// testAuth is an auth usecase backed by the in-memory repositories.
+type testAuth struct {
+	*authUsecase
+	cfg         *config.Config
+	userRepo    domainrepo.UserRepository
+	tokenRepo   domainrepo.OneTimeTokenRepository
+	inviteRepo  domainrepo.InviteRepository
+	sessionRepo domainrepo.SessionRepository
+}
+
+func newTestAuth(t *testing.T, configure func(cfg *config.Config)) *testAuth {
+	t.Helper()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Server.GinMode = "test"
+	cfg.WebAuthn = config.WebAuthnConfig{RPID: testRPID, RPName: "DevFolio", Origins: testOrigin}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	jwtManager, err := auth.NewJWTManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordManager, err := auth.NewPasswordManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := repositories.NewMemoryStore()
+	ta := &testAuth{
+		cfg:         cfg,
+		userRepo:    repositories.NewMemoryUserRepository(store),
+		tokenRepo:   repositories.NewMemoryOneTimeTokenRepository(store),
+		inviteRepo:  repositories.NewMemoryInviteRepository(store),
+		sessionRepo: repositories.NewMemorySessionRepository(store),
+	}
+	usecase, err := NewAuthUsecase(
+		ta.userRepo,
+		ta.sessionRepo,
+		ta.tokenRepo,
+		repositories.NewMemoryLoginAttemptRepository(store),
+		repositories.NewMemoryAuditEventRepository(store),
+		ta.inviteRepo,
+		jwtManager,
+		passwordManager,
+		discardMailer{},
+		cfg,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.authUsecase = usecase.(*authUsecase)
+
+	return ta
+}
+
+// discardMailer drops messages. Emails are sent in the background, so a
+// mailer writing files could outlive the test's temporary directory.
+type discardMailer struct{}
+
+func (discardMailer) Send(context.Context, *mail.Message) error { return nil }
+
+// createUser stores an active user without going through registration.
+func (ta *testAuth) createUser(t *testing.T, email string) *entities.User {
+	t.Helper()
+
+	user := &entities.User{Email: email, FirstName: "Test", LastName: "User", Role: entities.RoleUser}
+	if err := ta.userRepo.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// registerPasskey runs the registration ceremony for user with authenticator.
+func (ta *testAuth) registerPasskey(t *testing.T, user *entities.User, authenticator *webauthntest.Authenticator) {
+	t.Helper()
+	ctx := context.Background()
+
+	options, err := ta.BeginPasskeyRegistration(ctx, user.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.RP.ID != testRPID || options.User.ID != auth.PasskeyUserHandle(user.ID) {
+		t.Fatalf("unexpected creation options: %+v", options)
+	}
+
+	_, err = ta.FinishPasskeyRegistration(ctx, user.ID.Hex(), &entities.PasskeyRegistrationRequest{
+		Name: "Laptop",
+		Credential: entities.PasskeyRegistrationCredential{
+			ID:       authenticator.ID(),
+			Type:     "public-key",
+			Response: *authenticator.Register(options.Challenge),
+		},
+	})
+	if err != nil {
+		t.Fatalf("FinishPasskeyRegistration() error = %v", err)
+	}
+}
+
+func passkeyLoginRequest(authenticator *webauthntest.Authenticator, challenge string, user *entities.User) *entities.PasskeyLoginRequest {
+	return &entities.PasskeyLoginRequest{
+		ID:       authenticator.ID(),
+		Type:     "public-key",
+		Response: *authenticator.Login(challenge, auth.PasskeyUserHandle(user.ID)),
+	}
+}
+
+func TestPasskeyCeremonies(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, nil)
+	user := ta.createUser(t, "passkey@example.com")
+
+	authenticator, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.registerPasskey(t, user, authenticator)
+
+	passkeys, err := ta.ListPasskeys(ctx, user.ID.Hex())
+	if err != nil || len(passkeys) != 1 || passkeys[0].ID != authenticator.ID() || passkeys[0].Name != "Laptop" {
+		t.Fatalf("ListPasskeys() = %+v, %v", passkeys, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		options, err := ta.BeginPasskeyLogin(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loggedIn, tokens, err := ta.FinishPasskeyLogin(ctx, passkeyLoginRequest(authenticator, options.Challenge, user), &entities.ClientInfo{})
+		if err != nil {
+			t.Fatalf("FinishPasskeyLogin() error = %v", err)
+		}
+		if loggedIn.ID != user.ID || tokens == nil || tokens.AccessToken == "" {
+			t.Fatalf("FinishPasskeyLogin() = %v, %v", loggedIn.ID, tokens)
+		}
+	}
+
+	stored, err := ta.userRepo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Passkeys[0].SignCount != authenticator.SignCount || stored.Passkeys[0].LastUsedAt == nil {
+		t.Errorf("stored passkey = %+v, want sign count %d", stored.Passkeys[0], authenticator.SignCount)
+	}
+}
+
+func TestPasskeyRegistrationRejects(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, nil)
+	user := ta.createUser(t, "passkey@example.com")
+	other := ta.createUser(t, "other@example.com")
+
+	authenticator, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finish := func(userID, challenge string) error {
+		_, err := ta.FinishPasskeyRegistration(ctx, userID, &entities.PasskeyRegistrationRequest{
+			Credential: entities.PasskeyRegistrationCredential{
+				ID:       authenticator.ID(),
+				Type:     "public-key",
+				Response: *authenticator.Register(challenge),
+			},
+		})
+		return err
+	}
+
+	if err := finish(user.ID.Hex(), "never-issued"); err == nil {
+		t.Error("registration with an unknown challenge succeeded")
+	}
+
+	options, err := ta.BeginPasskeyRegistration(ctx, user.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := finish(other.ID.Hex(), options.Challenge); err == nil {
+		t.Error("registration finished by another user succeeded")
+	}
+
+	options, err = ta.BeginPasskeyRegistration(ctx, user.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator.Origin = "https://evil.test"
+	if err := finish(user.ID.Hex(), options.Challenge); err == nil {
+		t.Error("registration from another origin succeeded")
+	}
+
+	// The challenge was used up by the failed attempt
+	authenticator.Origin = testOrigin
+	if err := finish(user.ID.Hex(), options.Challenge); err == nil {
+		t.Error("registration challenge could be used twice")
+	}
+}
+
+func TestPasskeyLoginRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(a *webauthntest.Authenticator)
+		tamper  func(req *entities.PasskeyLoginRequest)
+		wantErr string
+	}{
+		{
+			name:    "wrong origin",
+			modify:  func(a *webauthntest.Authenticator) { a.Origin = "https://evil.test" },
+			wantErr: "unexpected origin",
+		},
+		{
+			name:    "wrong RP ID hash",
+			modify:  func(a *webauthntest.Authenticator) { a.RPID = "evil.test" },
+			wantErr: "relying party ID mismatch",
+		},
+		{
+			name:    "sign count regression",
+			modify:  func(a *webauthntest.Authenticator) { a.SignCount = 0 },
+			wantErr: "signature counter did not increase",
+		},
+		{
+			name: "bad signature",
+			tamper: func(req *entities.PasskeyLoginRequest) {
+				req.Response.Signature = strings.Repeat("A", len(req.Response.Signature))
+			},
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "unknown credential",
+			tamper:  func(req *entities.PasskeyLoginRequest) { req.ID = "unknown" },
+			wantErr: "passkey not recognized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ta := newTestAuth(t, nil)
+			user := ta.createUser(t, "passkey@example.com")
+
+			authenticator, err := webauthntest.New(testRPID, testOrigin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ta.registerPasskey(t, user, authenticator)
+
+			// One good login so the stored counter is above zero
+			options, err := ta.BeginPasskeyLogin(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			authenticator.SignCount = 5
+			if _, _, err := ta.FinishPasskeyLogin(ctx, passkeyLoginRequest(authenticator, options.Challenge, user), &entities.ClientInfo{}); err != nil {
+				t.Fatal(err)
+			}
+
+			options, err = ta.BeginPasskeyLogin(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.modify != nil {
+				tt.modify(authenticator)
+			}
+			req := passkeyLoginRequest(authenticator, options.Challenge, user)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			_, _, err = ta.FinishPasskeyLogin(ctx, req, &entities.ClientInfo{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("FinishPasskeyLogin() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginChallengeIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, nil)
+	user := ta.createUser(t, "passkey@example.com")
+
+	authenticator, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.registerPasskey(t, user, authenticator)
+
+	options, err := ta.BeginPasskeyLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ta.FinishPasskeyLogin(ctx, passkeyLoginRequest(authenticator, options.Challenge, user), &entities.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ta.FinishPasskeyLogin(ctx, passkeyLoginRequest(authenticator, options.Challenge, user), &entities.ClientInfo{}); err == nil {
+		t.Fatal("login challenge could be used twice")
+	}
+	if _, _, err := ta.FinishPasskeyLogin(ctx, passkeyLoginRequest(authenticator, "never-issued", user), &entities.ClientInfo{}); err == nil {
+		t.Fatal("login with an unknown challenge succeeded")
+	}
+}
+
+func TestLoginWithProviderRefusesDeactivatedAccount(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, nil)
+	user := ta.createUser(t, "banned@gmail.com")
+	if err := ta.userRepo.SetActive(ctx, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range []string{"banned@gmail.com", "b.a.n.n.e.d+new@gmail.com"} {
+		profile := &entities.ExternalProfile{Provider: "github", Subject: "42", Email: email, EmailVerified: true}
+		if _, _, err := ta.LoginWithProvider(ctx, profile, "", &entities.ClientInfo{}); !errors.Is(err, ErrAccountDeactivated) {
+			t.Errorf("LoginWithProvider(%s) error = %v, want ErrAccountDeactivated", email, err)
+		}
+	}
+
+	users, err := ta.userRepo.List(ctx, entities.UserFilter{}, 0, 0)
+	if err != nil || len(users) != 1 {
+		t.Fatalf("users = %d, %v, want only the deactivated one", len(users), err)
+	}
+}
+
+func TestRegisterRefusesDeactivatedAccount(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, nil)
+	user := ta.createUser(t, "banned@gmail.com")
+	if err := ta.userRepo.SetActive(ctx, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range []string{"banned@gmail.com", "b.a.n.n.e.d+new@gmail.com"} {
+		if _, _, err := ta.Register(ctx, registerRequest(email, ""), &entities.ClientInfo{}); !errors.Is(err, ErrAccountDeactivated) {
+			t.Errorf("Register(%s) error = %v, want ErrAccountDeactivated", email, err)
+		}
+	}
+
+	if exists, _ := ta.userRepo.EmailExists(ctx, user.Email); exists {
+		t.Fatal("Register() created a new account for the address")
+	}
+}
+
+// magicLinkToken stores a sign-in link for email the way RequestMagicLink does.
+func (ta *testAuth) magicLinkToken(t *testing.T, email string) string {
+	t.Helper()
+
+	token, _, err := ta.jwtManager.GenerateActionToken("", email, auth.MagicLinkTokenType, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ta.tokenRepo.Create(context.Background(), &entities.OneTimeToken{
+		Email:     email,
+		Purpose:   entities.TokenPurposeMagicLink,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestLoginWithMagicLinkRefusesDeactivatedAccount(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, nil)
+	user := ta.createUser(t, "banned@example.com")
+	if err := ta.userRepo.SetActive(ctx, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := ta.LoginWithMagicLink(ctx, ta.magicLinkToken(t, user.Email), "", &entities.ClientInfo{})
+	if !errors.Is(err, ErrAccountDeactivated) {
+		t.Fatalf("LoginWithMagicLink() error = %v, want ErrAccountDeactivated", err)
+	}
+	if exists, _ := ta.userRepo.EmailExists(ctx, user.Email); exists {
+		t.Fatal("LoginWithMagicLink() created a new account for the address")
+	}
+}
+
+func TestRequestMagicLinkLimits(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, func(cfg *config.Config) { cfg.LoginThrottle.Enabled = true })
+	client := &entities.ClientInfo{IPAddress: "203.0.113.7"}
+
+	request := func(email string) error {
+		return ta.RequestMagicLink(ctx, &entities.MagicLinkRequest{Email: email}, client)
+	}
+
+	if err := request("first@example.com"); err != nil {
+		t.Fatalf("RequestMagicLink() error = %v", err)
+	}
+	var throttled *LoginThrottledError
+	if err := request("First@example.com"); !errors.As(err, &throttled) || throttled.RetryAfter <= 0 {
+		t.Fatalf("second link for the address: error = %v, want LoginThrottledError", err)
+	}
+
+	// Both requests so far count against the IP
+	for i := 2; i < magicLinkIPLimit; i++ {
+		if err := request(fmt.Sprintf("user%d@example.com", i)); err != nil {
+			t.Fatalf("request %d from the IP: error = %v", i+1, err)
+		}
+	}
+	if err := request("another@example.com"); !errors.As(err, &throttled) {
+		t.Fatalf("request over the IP limit: error = %v, want LoginThrottledError", err)
+	}
+
+	other := &entities.ClientInfo{IPAddress: "198.51.100.1"}
+	if err := ta.RequestMagicLink(ctx, &entities.MagicLinkRequest{Email: "another@example.com"}, other); err != nil {
+		t.Fatalf("request from another IP: error = %v", err)
+	}
+}
+
+func TestLoginThrottleCountsAddressVariantsTogether(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, func(cfg *config.Config) {
+		cfg.LoginThrottle.Enabled = true
+		cfg.LoginThrottle.FreeAttempts = 10
+		cfg.LoginThrottle.LockoutThreshold = 3
+	})
+
+	user := ta.createUser(t, "ab@gmail.com")
+	hash, err := ta.passwordManager.HashPassword("the right passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Password = hash
+	if err := ta.userRepo.Update(ctx, user.ID, user); err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(email, password string) error {
+		_, _, err := ta.Login(ctx, &entities.LoginRequest{Email: email, Password: password}, nil)
+		return err
+	}
+
+	for _, email := range []string{"a.b+x@gmail.com", "ab@gmail.com", "A.B@googlemail.com"} {
+		if err := login(email, "a wrong guess"); err == nil {
+			t.Fatalf("Login(%s) with a wrong password succeeded", email)
+		}
+	}
+
+	var throttled *LoginThrottledError
+	if err := login("ab+other@gmail.com", "the right passphrase"); !errors.As(err, &throttled) {
+		t.Fatalf("Login() after failures across address variants: error = %v, want LoginThrottledError", err)
+	}
+
+	if err := ta.RequestMagicLink(ctx, &entities.MagicLinkRequest{Email: "a.b+x@gmail.com"}, nil); err != nil {
+		t.Fatalf("RequestMagicLink() error = %v", err)
+	}
+	if err := ta.RequestMagicLink(ctx, &entities.MagicLinkRequest{Email: "ab@gmail.com"}, nil); !errors.As(err, &throttled) {
+		t.Fatalf("link for another variant of the address: error = %v, want LoginThrottledError", err)
+	}
+}
+
+func TestBeginPasskeyLoginLimitsIP(t *testing.T) {
+	ctx := context.Background()
+	ta := newTestAuth(t, func(cfg *config.Config) { cfg.LoginThrottle.Enabled = true })
+	client := &entities.ClientInfo{IPAddress: "203.0.113.7"}
+
+	for i := 0; i < passkeyLoginIPLimit; i++ {
+		if _, err := ta.BeginPasskeyLogin(ctx, client); err != nil {
+			t.Fatalf("passkey sign-in %d from the IP: error = %v", i+1, err)
+		}
+	}
+	var throttled *LoginThrottledError
+	if _, err := ta.BeginPasskeyLogin(ctx, client); !errors.As(err, &throttled) {
+		t.Fatalf("passkey sign-in over the IP limit: error = %v, want LoginThrottledError", err)
+	}
+
+	if _, err := ta.BeginPasskeyLogin(ctx, &entities.ClientInfo{IPAddress: "198.51.100.1"}); err != nil {
+		t.Fatalf("passkey sign-in from another IP: error = %v", err)
+	}
+}
@@ -0,0 +1,444 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	dataExportFormatVersion = 1
+	// exportBuildTimeout bounds one archive build; pending exports older than
+	// this were interrupted, e.g. by a restart, and are treated as failed
+	exportBuildTimeout = 10 * time.Minute
+	// exportCooldown is how long a finished export is handed out again instead
+	// of building a new one
+	exportCooldown = time.Hour
+)
+
+// exportExclusions is listed in every manifest, so the archive says what it
+// leaves out as well as what it holds.
+var exportExclusions = []entities.DataExportManifestExclusion{
+	{
+		Data:   "Avatar and project images",
+		Reason: "DevFolio does not store uploaded media. Images are kept as links, which are included in profile.json and portfolios.json, but the files behind them are not.",
+	},
+	{
+		Data:   "Passwords, token values, two-factor secrets and passkey keys",
+		Reason: "Only hashes or public keys are stored, and they are left out so the archive cannot be used to sign in.",
+	},
+}
+
+type DataExportUsecase interface {
+	// RequestExport starts building an archive in the background and returns
+	// its record right away. Recent or still running exports are reused.
+	RequestExport(ctx context.Context, userID string) (*entities.DataExport, error)
+	// GetExport returns the status of an export, with a fresh download link once it is ready.
+	GetExport(ctx context.Context, userID, exportID string) (*entities.DataExport, error)
+	// OpenExport checks a download link and returns the path of the archive.
+	OpenExport(ctx context.Context, exportID, token string) (string, error)
+	// DeleteExpiredExports removes expired exports and their archives.
+	DeleteExpiredExports(ctx context.Context) (int, error)
+	// RunCleanup calls DeleteExpiredExports periodically until ctx is done.
+	RunCleanup(ctx context.Context)
+}
+
+type dataExportUsecase struct {
+	exportRepo      repositories.DataExportRepository
+	userRepo        repositories.UserRepository
+	portfolioRepo   repositories.PortfolioRepository
+	sessionRepo     repositories.SessionRepository
+	tokenRepo       repositories.PersonalAccessTokenRepository
+	auditRepo       repositories.AuditEventRepository
+	files           *storage.FileStore
+	jwtManager      *auth.JWTManager
+	expiry          time.Duration
+	linkExpiry      time.Duration
+	cleanupInterval time.Duration
+}
+
+func NewDataExportUsecase(
+	exportRepo repositories.DataExportRepository,
+	userRepo repositories.UserRepository,
+	portfolioRepo repositories.PortfolioRepository,
+	sessionRepo repositories.SessionRepository,
+	tokenRepo repositories.PersonalAccessTokenRepository,
+	auditRepo repositories.AuditEventRepository,
+	files *storage.FileStore,
+	jwtManager *auth.JWTManager,
+	cfg *config.Config,
+) (DataExportUsecase, error) {
+	expiry, err := time.ParseDuration(cfg.Export.Expiry)
+	if err != nil || expiry <= 0 {
+		return nil, fmt.Errorf("invalid data export expiry %q", cfg.Export.Expiry)
+	}
+
+	linkExpiry, err := time.ParseDuration(cfg.Export.LinkExpiry)
+	if err != nil || linkExpiry <= 0 {
+		return nil, fmt.Errorf("invalid data export link expiry %q", cfg.Export.LinkExpiry)
+	}
+
+	cleanupInterval, err := time.ParseDuration(cfg.Export.CleanupInterval)
+	if err != nil || cleanupInterval <= 0 {
+		return nil, fmt.Errorf("invalid data export cleanup interval %q", cfg.Export.CleanupInterval)
+	}
+
+	return &dataExportUsecase{
+		exportRepo:      exportRepo,
+		userRepo:        userRepo,
+		portfolioRepo:   portfolioRepo,
+		sessionRepo:     sessionRepo,
+		tokenRepo:       tokenRepo,
+		auditRepo:       auditRepo,
+		files:           files,
+		jwtManager:      jwtManager,
+		expiry:          expiry,
+		linkExpiry:      linkExpiry,
+		cleanupInterval: cleanupInterval,
+	}, nil
+}
+
+func (u *dataExportUsecase) RequestExport(ctx context.Context, userID string) (*entities.DataExport, error) {
+	if latest, err := u.exportRepo.GetLatestByUserID(ctx, userID); err == nil && !latest.IsExpired() {
+		switch {
+		case latest.Status == entities.DataExportPending && time.Since(latest.CreatedAt) < exportBuildTimeout:
+			return latest, nil
+		case latest.Status == entities.DataExportReady && time.Since(latest.CreatedAt) < exportCooldown:
+			return u.withDownloadURL(latest)
+		}
+	}
+
+	export := &entities.DataExport{
+		UserID:    userID,
+		Status:    entities.DataExportPending,
+		ExpiresAt: time.Now().Add(u.expiry),
+	}
+	if err := u.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	// The build outlives the request, so it gets its own context
+	go u.buildExport(*export)
+
+	return export, nil
+}
+
+func (u *dataExportUsecase) GetExport(ctx context.Context, userID, exportID string) (*entities.DataExport, error) {
+	export, err := u.getOwnExport(ctx, userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Status == entities.DataExportPending && time.Since(export.CreatedAt) >= exportBuildTimeout {
+		export.Status = entities.DataExportFailed
+		export.Error = "export was interrupted, please request a new one"
+	}
+	if export.Status != entities.DataExportReady {
+		return export, nil
+	}
+
+	return u.withDownloadURL(export)
+}
+
+func (u *dataExportUsecase) OpenExport(ctx context.Context, exportID, token string) (string, error) {
+	claims, err := u.jwtManager.ValidateActionToken(token, auth.DataExportTokenType)
+	// A link only opens the export it was issued for
+	if err != nil || claims.ResourceID != exportID {
+		return "", fmt.Errorf("invalid or expired download link")
+	}
+
+	export, err := u.getOwnExport(ctx, claims.UserID, exportID)
+	if err != nil {
+		return "", err
+	}
+	if export.Status != entities.DataExportReady {
+		return "", fmt.Errorf("export is not ready")
+	}
+
+	// Links stop working as soon as the account is deactivated or deleted
+	if _, err := u.getUser(ctx, claims.UserID); err != nil {
+		return "", fmt.Errorf("invalid or expired download link")
+	}
+
+	return u.files.Path(export.FileName)
+}
+
+func (u *dataExportUsecase) DeleteExpiredExports(ctx context.Context) (int, error) {
+	exports, err := u.exportRepo.GetExpired(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, export := range exports {
+		if export.FileName != "" {
+			if err := u.files.Remove(export.FileName); err != nil {
+				fmt.Printf("Failed to remove export archive %s: %v\n", export.ID.Hex(), err)
+				continue
+			}
+		}
+		if err := u.exportRepo.Delete(ctx, export.ID); err != nil {
+			fmt.Printf("Failed to delete export %s: %v\n", export.ID.Hex(), err)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (u *dataExportUsecase) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(u.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := u.DeleteExpiredExports(ctx); err != nil {
+			fmt.Printf("Failed to delete expired exports: %v\n", err)
+		} else if deleted > 0 {
+			fmt.Printf("Deleted %d expired exports\n", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// getOwnExport loads an export, hiding exports of other users and expired ones.
+func (u *dataExportUsecase) getOwnExport(ctx context.Context, userID, exportID string) (*entities.DataExport, error) {
+	id, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return nil, fmt.Errorf("export not found")
+	}
+
+	export, err := u.exportRepo.GetByID(ctx, id)
+	if err != nil || export.UserID != userID || export.IsExpired() {
+		return nil, fmt.Errorf("export not found")
+	}
+
+	return export, nil
+}
+
+func (u *dataExportUsecase) getUser(ctx context.Context, userID string) (*entities.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := u.userRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return user, nil
+}
+
+// withDownloadURL sets a signed download link that expires after linkExpiry,
+// or with the export if that comes first.
+func (u *dataExportUsecase) withDownloadURL(export *entities.DataExport) (*entities.DataExport, error) {
+	ttl := u.linkExpiry
+	if remaining := time.Until(export.ExpiresAt); remaining < ttl {
+		ttl = remaining
+	}
+
+	token, err := u.jwtManager.GenerateResourceToken(export.UserID, export.ID.Hex(), auth.DataExportTokenType, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download link: %w", err)
+	}
+
+	export.DownloadURL = fmt.Sprintf("/api/v1/auth/exports/%s/download?token=%s", export.ID.Hex(), url.QueryEscape(token))
+	return export, nil
+}
+
+func (u *dataExportUsecase) buildExport(export entities.DataExport) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportBuildTimeout)
+	defer cancel()
+
+	fileName := export.ID.Hex() + ".zip"
+	size, err := u.writeArchive(ctx, &export, fileName)
+	if err != nil {
+		fmt.Printf("Failed to build export %s: %v\n", export.ID.Hex(), err)
+		if err := u.files.Remove(fileName); err != nil {
+			fmt.Printf("Failed to remove export archive %s: %v\n", export.ID.Hex(), err)
+		}
+		export.Status = entities.DataExportFailed
+		export.Error = "failed to build export"
+	} else {
+		export.Status = entities.DataExportReady
+		export.FileName = fileName
+		export.Size = size
+	}
+
+	completedAt := time.Now()
+	export.CompletedAt = &completedAt
+	if err := u.exportRepo.Update(ctx, &export); err != nil {
+		fmt.Printf("Failed to update export %s: %v\n", export.ID.Hex(), err)
+	}
+}
+
+// exportFile is one JSON document in the archive.
+type exportFile struct {
+	name        string
+	description string
+	records     int
+	data        interface{}
+}
+
+func (u *dataExportUsecase) writeArchive(ctx context.Context, export *entities.DataExport, fileName string) (int64, error) {
+	files, err := u.collectExportFiles(ctx, export.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := u.files.Create(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	manifest := entities.DataExportManifest{
+		FormatVersion: dataExportFormatVersion,
+		ExportID:      export.ID.Hex(),
+		UserID:        export.UserID,
+		GeneratedAt:   time.Now().UTC(),
+		Files:         []entities.DataExportManifestFile{},
+		Excluded:      exportExclusions,
+	}
+
+	archive := zip.NewWriter(out)
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+		if err := writeZipEntry(archive, file.name, content); err != nil {
+			return 0, err
+		}
+
+		checksum := sha256.Sum256(content)
+		manifest.Files = append(manifest.Files, entities.DataExportManifestFile{
+			Name:        file.name,
+			Description: file.description,
+			Records:     file.records,
+			Size:        len(content),
+			SHA256:      hex.EncodeToString(checksum[:]),
+		})
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeZipEntry(archive, "manifest.json", content); err != nil {
+		return 0, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return info.Size(), nil
+}
+
+// collectExportFiles gathers everything stored about the user. Secrets such as
+// password hashes, token hashes and passkey public keys are left out.
+func (u *dataExportUsecase) collectExportFiles(ctx context.Context, userID string) ([]exportFile, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolios, err := u.portfolioRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolios: %w", err)
+	}
+	if portfolios == nil {
+		portfolios = []*entities.Portfolio{}
+	}
+
+	sessions, err := u.sessionRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	sessionResponses := make([]*entities.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, session.ToResponse(""))
+	}
+
+	tokens, err := u.tokenRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access tokens: %w", err)
+	}
+
+	events, err := u.auditRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+
+	identities := user.Identities
+	if identities == nil {
+		identities = []entities.LinkedIdentity{}
+	}
+	passkeys := user.Passkeys
+	if passkeys == nil {
+		passkeys = []entities.PasskeyCredential{}
+	}
+	devices := user.KnownDevices
+	if devices == nil {
+		devices = []entities.KnownDevice{}
+	}
+
+	return []exportFile{
+		{name: "profile.json", description: "Account profile", records: 1, data: user.ToResponse()},
+		{name: "portfolios.json", description: "Portfolios, published or not", records: len(portfolios), data: portfolios},
+		{name: "sessions.json", description: "Signed-in sessions", records: len(sessionResponses), data: sessionResponses},
+		{name: "identities.json", description: "Linked sign-in providers", records: len(identities), data: identities},
+		{name: "passkeys.json", description: "Registered passkeys", records: len(passkeys), data: passkeys},
+		{name: "known_devices.json", description: "Devices and networks you have signed in from", records: len(devices), data: devices},
+		{name: "access_tokens.json", description: "Personal access tokens, without the token values", records: len(tokens), data: tokens},
+		{name: "audit_events.json", description: "Security activity such as sign-ins and password changes", records: len(events), data: events},
+	}, nil
+}
+
+func writeZipEntry(archive *zip.Writer, name string, content []byte) error {
+	writer, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	if _, err := writer.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
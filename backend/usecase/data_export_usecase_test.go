@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/config"
+	"devfolio-backend/infrastructure/storage"
+	"devfolio-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestExports(t *testing.T, dir string) (*dataExportUsecase, domainrepo.UserRepository, domainrepo.DataExportRepository) {
+	t.Helper()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Server.GinMode = "test"
+	jwtManager, err := auth.NewJWTManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := repositories.NewMemoryStore()
+	userRepo := repositories.NewMemoryUserRepository(store)
+	exportRepo := repositories.NewMemoryDataExportRepository(store)
+	files := storage.NewFileStore(dir)
+	exports, err := NewDataExportUsecase(
+		exportRepo,
+		userRepo,
+		repositories.NewMemoryPortfolioRepository(store),
+		repositories.NewMemorySessionRepository(store),
+		repositories.NewMemoryPersonalAccessTokenRepository(store),
+		repositories.NewMemoryAuditEventRepository(store),
+		files,
+		jwtManager,
+		cfg,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exports.(*dataExportUsecase), userRepo, exportRepo
+}
+
+func TestDownloadLinkOnlyOpensItsExport(t *testing.T) {
+	ctx := context.Background()
+	exports, userRepo, exportRepo := newTestExports(t, t.TempDir())
+
+	user := &entities.User{Email: "export@example.com", Role: entities.RoleUser}
+	if err := userRepo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, name := range []string{"first.zip", "second.zip"} {
+		export := &entities.DataExport{UserID: user.ID.Hex(), Status: entities.DataExportReady, FileName: name, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := exportRepo.Create(ctx, export); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, export.ID.Hex())
+	}
+
+	first, err := exports.GetExport(ctx, user.ID.Hex(), ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := url.Parse(first.DownloadURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := link.Query().Get("token")
+
+	if _, err := exports.OpenExport(ctx, ids[0], token); err != nil {
+		t.Fatalf("OpenExport() with its own link error = %v", err)
+	}
+	if _, err := exports.OpenExport(ctx, ids[1], token); err == nil {
+		t.Fatal("OpenExport() accepted a link issued for another export")
+	}
+}
+
+func TestManifestListsExcludedData(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	exports, userRepo, _ := newTestExports(t, dir)
+
+	user := &entities.User{Email: "export@example.com", Role: entities.RoleUser}
+	if err := userRepo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	export := &entities.DataExport{ID: primitive.NewObjectID(), UserID: user.ID.Hex()}
+	if _, err := exports.writeArchive(ctx, export, "export.zip"); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.OpenReader(filepath.Join(dir, "export.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	file, err := archive.Open("manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var manifest entities.DataExportManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Excluded) != len(exportExclusions) || manifest.Excluded[0].Data != "Avatar and project images" {
+		t.Fatalf("manifest exclusions = %+v, want %+v", manifest.Excluded, exportExclusions)
+	}
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// versionPattern matches the version numbers in a user agent string.
+var versionPattern = regexp.MustCompile(`\d+([._]\d+)*`)
+
+// deviceFingerprint identifies a browser and platform by its user agent with
+// the version numbers removed, so the fingerprint survives updates.
+func deviceFingerprint(userAgent string) string {
+	normalized := versionPattern.ReplaceAllString(strings.ToLower(strings.TrimSpace(userAgent)), "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:16])
+}
+
+// networkPrefix reduces an IP address to its /24 (IPv4) or /48 (IPv6) network,
+// which changes less often than the address itself.
+func networkPrefix(ipAddress string) string {
+	ip := net.ParseIP(strings.TrimSpace(ipAddress))
+	if ip == nil {
+		return ipAddress
+	}
+
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
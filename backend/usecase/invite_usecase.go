@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultInviteLifetimeDays = 7
+
+// InviteUsecase lets admins hand out invite codes for invite-only registration.
+type InviteUsecase interface {
+	CreateInvite(ctx context.Context, actorID string, req *entities.CreateInviteRequest) (*entities.CreateInviteResponse, error)
+	ListInvites(ctx context.Context, limit, offset int) ([]*entities.Invite, error)
+	RevokeInvite(ctx context.Context, id string) error
+}
+
+type inviteUsecase struct {
+	inviteRepo repositories.InviteRepository
+}
+
+func NewInviteUsecase(inviteRepo repositories.InviteRepository) InviteUsecase {
+	return &inviteUsecase{inviteRepo: inviteRepo}
+}
+
+func (u *inviteUsecase) CreateInvite(ctx context.Context, actorID string, req *entities.CreateInviteRequest) (*entities.CreateInviteResponse, error) {
+	maxUses := req.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultInviteLifetimeDays
+	}
+
+	code, err := auth.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	invite := &entities.Invite{
+		CodeHash:  auth.HashToken(code),
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		Note:      strings.TrimSpace(req.Note),
+		MaxUses:   maxUses,
+		CreatedBy: actorID,
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	if err := u.inviteRepo.Create(ctx, invite); err != nil {
+		return nil, fmt.Errorf("failed to store invite: %w", err)
+	}
+
+	return &entities.CreateInviteResponse{Invite: invite, Code: code}, nil
+}
+
+func (u *inviteUsecase) ListInvites(ctx context.Context, limit, offset int) ([]*entities.Invite, error) {
+	limit, offset = clampPage(limit, offset)
+
+	invites, err := u.inviteRepo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+func (u *inviteUsecase) RevokeInvite(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid invite ID: %w", err)
+	}
+
+	return u.inviteRepo.Revoke(ctx, objectID)
+}
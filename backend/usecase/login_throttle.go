@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/config"
+)
+
+// LoginThrottledError is returned when sign-in is refused because of too many
+// recent failures for the email address or client IP.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	// Message replaces the default explanation when a limit on other sign-in
+	// requests was hit
+	Message string
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "too many failed login attempts, try again later"
+}
+
+// loginThrottle slows down password and second factor guessing. Each email
+// address and client IP gets a few free failures, then every further failure
+// doubles the wait before the next attempt, and past a threshold the key is
+// locked out for a fixed time.
+type loginThrottle struct {
+	repo               repositories.LoginAttemptRepository
+	enabled            bool
+	freeAttempts       int
+	ipFreeAttempts     int
+	baseDelay          time.Duration
+	maxDelay           time.Duration
+	lockoutThreshold   int
+	ipLockoutThreshold int
+	lockoutDuration    time.Duration
+	window             time.Duration
+}
+
+type throttleKey struct {
+	key          string
+	freeAttempts int
+	threshold    int
+}
+
+func newLoginThrottle(repo repositories.LoginAttemptRepository, cfg *config.Config) (*loginThrottle, error) {
+	durations := map[string]string{
+		"base delay":       cfg.LoginThrottle.BaseDelay,
+		"max delay":        cfg.LoginThrottle.MaxDelay,
+		"lockout duration": cfg.LoginThrottle.LockoutDuration,
+		"window":           cfg.LoginThrottle.Window,
+	}
+	parsed := make(map[string]time.Duration, len(durations))
+	for name, value := range durations {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid login throttle %s: %w", name, err)
+		}
+		parsed[name] = d
+	}
+
+	return &loginThrottle{
+		repo:               repo,
+		enabled:            cfg.LoginThrottle.Enabled,
+		freeAttempts:       cfg.LoginThrottle.FreeAttempts,
+		ipFreeAttempts:     cfg.LoginThrottle.IPFreeAttempts,
+		baseDelay:          parsed["base delay"],
+		maxDelay:           parsed["max delay"],
+		lockoutThreshold:   cfg.LoginThrottle.LockoutThreshold,
+		ipLockoutThreshold: cfg.LoginThrottle.IPLockoutThreshold,
+		lockoutDuration:    parsed["lockout duration"],
+		window:             parsed["window"],
+	}, nil
+}
+
+// emailAttemptKey is the key failed sign-ins are counted under for an address.
+// Variants of an address that reach the same account share one key.
+func emailAttemptKey(email string) string {
+	return "email:" + entities.CanonicalEmail(email)
+}
+
+func (t *loginThrottle) keys(email string, client *entities.ClientInfo) []throttleKey {
+	keys := []throttleKey{{
+		key:          emailAttemptKey(email),
+		freeAttempts: t.freeAttempts,
+		threshold:    t.lockoutThreshold,
+	}}
+	if client != nil && client.IPAddress != "" {
+		keys = append(keys, throttleKey{
+			key:          "ip:" + client.IPAddress,
+			freeAttempts: t.ipFreeAttempts,
+			threshold:    t.ipLockoutThreshold,
+		})
+	}
+	return keys
+}
+
+// check returns a LoginThrottledError if the email address or IP must wait
+// before trying again. It runs before the password is hashed, so throttled
+// guesses cost no password hashing work.
+func (t *loginThrottle) check(ctx context.Context, email string, client *entities.ClientInfo) error {
+	if !t.enabled {
+		return nil
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range t.keys(email, client) {
+		attempt, err := t.repo.Get(ctx, k.key)
+		if err != nil {
+			continue
+		}
+		if d := t.blockedUntil(attempt, k).Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt against the email address and IP,
+// locking either one out once it reaches its threshold.
+func (t *loginThrottle) recordFailure(ctx context.Context, email string, client *entities.ClientInfo) {
+	if !t.enabled {
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(t.window)
+	if lockoutEnd := now.Add(t.lockoutDuration); lockoutEnd.After(expiresAt) {
+		expiresAt = lockoutEnd
+	}
+
+	for _, k := range t.keys(email, client) {
+		attempt, err := t.repo.RecordFailure(ctx, k.key, expiresAt)
+		if err != nil {
+			fmt.Printf("Failed to record login failure for %s: %v\n", k.key, err)
+			continue
+		}
+
+		if k.threshold > 0 && attempt.Failures >= k.threshold &&
+			(attempt.LockedUntil == nil || !attempt.LockedUntil.After(now)) {
+			if err := t.repo.Lock(ctx, k.key, now.Add(t.lockoutDuration)); err != nil {
+				fmt.Printf("Failed to lock out %s: %v\n", k.key, err)
+			}
+		}
+	}
+}
+
+// recordSuccess clears the failures for the email address. The IP count is
+// left to expire, otherwise signing in to one's own account would reset it.
+func (t *loginThrottle) recordSuccess(ctx context.Context, email string) {
+	if !t.enabled {
+		return
+	}
+
+	key := t.keys(email, nil)[0].key
+	if err := t.repo.Reset(ctx, key); err != nil {
+		fmt.Printf("Failed to reset login attempts for %s: %v\n", key, err)
+	}
+}
+
+// limitRequests allows limit requests for key until window has passed since
+// the last allowed one. Unlike failures, allowed requests count too: it is for
+// requests that send email or store state. Refused requests do not extend the
+// window, so flooding one address does not keep its owner locked out.
+func (t *loginThrottle) limitRequests(ctx context.Context, key string, limit int, window time.Duration, message string) error {
+	if !t.enabled {
+		return nil
+	}
+
+	if attempt, err := t.repo.Get(ctx, key); err == nil && attempt.Failures >= limit {
+		return &LoginThrottledError{RetryAfter: time.Until(attempt.ExpiresAt), Message: message}
+	}
+
+	if _, err := t.repo.RecordFailure(ctx, key, time.Now().Add(window)); err != nil {
+		fmt.Printf("Failed to record request for %s: %v\n", key, err)
+	}
+	return nil
+}
+
+func (t *loginThrottle) blockedUntil(attempt *entities.LoginAttempt, k throttleKey) time.Time {
+	var until time.Time
+	if attempt.LockedUntil != nil {
+		until = *attempt.LockedUntil
+	}
+
+	if excess := attempt.Failures - k.freeAttempts; excess > 0 {
+		delay := t.maxDelay
+		// Stop doubling well before the shift could overflow
+		if excess <= 30 {
+			if d := t.baseDelay << (excess - 1); d > 0 && d < t.maxDelay {
+				delay = d
+			}
+		}
+		if backoffEnd := attempt.LastFailureAt.Add(delay); backoffEnd.After(until) {
+			until = backoffEnd
+		}
+	}
+
+	return until
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultTokenLifetimeDays = 30
+	maxTokensPerUser         = 50
+	// lastUsedResolution limits last-used writes to one per token per minute
+	lastUsedResolution = time.Minute
+)
+
+type PersonalAccessTokenUsecase interface {
+	CreateToken(ctx context.Context, userID string, req *entities.CreatePersonalAccessTokenRequest) (*entities.CreatePersonalAccessTokenResponse, error)
+	ListTokens(ctx context.Context, userID string) ([]*entities.PersonalAccessToken, error)
+	RevokeToken(ctx context.Context, userID, tokenID string) error
+	// Authenticate returns the token and its owner if the token is valid.
+	Authenticate(ctx context.Context, token string) (*entities.PersonalAccessToken, *entities.User, error)
+}
+
+type personalAccessTokenUsecase struct {
+	tokenRepo repositories.PersonalAccessTokenRepository
+	userRepo  repositories.UserRepository
+}
+
+func NewPersonalAccessTokenUsecase(
+	tokenRepo repositories.PersonalAccessTokenRepository,
+	userRepo repositories.UserRepository,
+) PersonalAccessTokenUsecase {
+	return &personalAccessTokenUsecase{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+	}
+}
+
+func (u *personalAccessTokenUsecase) CreateToken(ctx context.Context, userID string, req *entities.CreatePersonalAccessTokenRequest) (*entities.CreatePersonalAccessTokenResponse, error) {
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := u.tokenRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	if len(existing) >= maxTokensPerUser {
+		return nil, fmt.Errorf("you can have at most %d personal access tokens", maxTokensPerUser)
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultTokenLifetimeDays
+	}
+
+	secret, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	value := entities.PersonalAccessTokenPrefix + secret
+
+	token := &entities.PersonalAccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Scopes:    scopes,
+		Prefix:    value[:len(entities.PersonalAccessTokenPrefix)+6],
+		TokenHash: auth.HashToken(value),
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	if err := u.tokenRepo.Create(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return &entities.CreatePersonalAccessTokenResponse{PersonalAccessToken: token, Token: value}, nil
+}
+
+func (u *personalAccessTokenUsecase) ListTokens(ctx context.Context, userID string) ([]*entities.PersonalAccessToken, error) {
+	tokens, err := u.tokenRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (u *personalAccessTokenUsecase) RevokeToken(ctx context.Context, userID, tokenID string) error {
+	objectID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return fmt.Errorf("invalid token ID: %w", err)
+	}
+
+	return u.tokenRepo.Delete(ctx, objectID, userID)
+}
+
+func (u *personalAccessTokenUsecase) Authenticate(ctx context.Context, value string) (*entities.PersonalAccessToken, *entities.User, error) {
+	if !strings.HasPrefix(value, entities.PersonalAccessTokenPrefix) {
+		return nil, nil, fmt.Errorf("not a personal access token")
+	}
+
+	token, err := u.tokenRepo.GetByHash(ctx, auth.HashToken(value))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+	if token.IsExpired() {
+		return nil, nil, fmt.Errorf("token has expired")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(token.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+	// Tokens of deactivated users stop working with them
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := u.tokenRepo.UpdateLastUsed(ctx, token.ID, now); err != nil {
+			fmt.Printf("Failed to record use of token %s: %v\n", token.ID.Hex(), err)
+		}
+		token.LastUsedAt = &now
+	}
+
+	return token, user, nil
+}
+
+// normalizeScopes rejects unknown scopes and drops duplicates.
+func normalizeScopes(requested []string) ([]string, error) {
+	seen := make(map[string]bool, len(requested))
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if !contains(entities.PersonalAccessTokenScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes, nil
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"devfolio-backend/domain/entities"
@@ -15,22 +16,38 @@ type PortfolioUsecase interface {
 	CreatePortfolio(ctx context.Context, req *entities.CreatePortfolioRequest, userID string) (*entities.Portfolio, error)
 	GetPortfolio(ctx context.Context, id string, requesterID string) (*entities.Portfolio, error)
 	GetUserPortfolios(ctx context.Context, userID string) ([]*entities.Portfolio, error)
-	UpdatePortfolio(ctx context.Context, id string, req *entities.UpdatePortfolioRequest, userID string) (*entities.Portfolio, error)
+	UpdatePortfolio(ctx context.Context, id string, req *entities.UpdatePortfolioRequest, userID string, client *entities.ClientInfo) (*entities.Portfolio, error)
 	DeletePortfolio(ctx context.Context, id string, userID string) error
 	GetPublicPortfolios(ctx context.Context, limit, offset int) ([]*entities.Portfolio, error)
 	SearchPortfolios(ctx context.Context, query string, limit, offset int) ([]*entities.Portfolio, error)
 	EnhanceWithAI(ctx context.Context, req *entities.AIEnhanceRequest, userID string) (*entities.Portfolio, error)
 }
 
+// ErrEmailNotVerified is returned when an unverified account tries to publish a
+// portfolio while auth.require_verified_email_to_publish is enabled.
+var ErrEmailNotVerified = errors.New("verify your email address before publishing a portfolio")
+
 type portfolioUsecase struct {
-	portfolioRepo repositories.PortfolioRepository
-	aiClient      *ai.OpenAIClient
+	portfolioRepo            repositories.PortfolioRepository
+	userRepo                 repositories.UserRepository
+	audit                    *auditLog
+	aiClient                 *ai.OpenAIClient
+	requireVerifiedToPublish bool
 }
 
-func NewPortfolioUsecase(portfolioRepo repositories.PortfolioRepository, aiClient *ai.OpenAIClient) PortfolioUsecase {
+func NewPortfolioUsecase(
+	portfolioRepo repositories.PortfolioRepository,
+	userRepo repositories.UserRepository,
+	auditRepo repositories.AuditEventRepository,
+	aiClient *ai.OpenAIClient,
+	requireVerifiedToPublish bool,
+) PortfolioUsecase {
 	return &portfolioUsecase{
-		portfolioRepo: portfolioRepo,
-		aiClient:      aiClient,
+		portfolioRepo:            portfolioRepo,
+		userRepo:                 userRepo,
+		audit:                    newAuditLog(auditRepo),
+		aiClient:                 aiClient,
+		requireVerifiedToPublish: requireVerifiedToPublish,
 	}
 }
 
@@ -87,7 +104,7 @@ func (u *portfolioUsecase) GetUserPortfolios(ctx context.Context, userID string)
 	return portfolios, nil
 }
 
-func (u *portfolioUsecase) UpdatePortfolio(ctx context.Context, id string, req *entities.UpdatePortfolioRequest, userID string) (*entities.Portfolio, error) {
+func (u *portfolioUsecase) UpdatePortfolio(ctx context.Context, id string, req *entities.UpdatePortfolioRequest, userID string, client *entities.ClientInfo) (*entities.Portfolio, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, fmt.Errorf("invalid portfolio ID: %w", err)
@@ -103,50 +120,15 @@ func (u *portfolioUsecase) UpdatePortfolio(ctx context.Context, id string, req *
 		return nil, fmt.Errorf("unauthorized: portfolio belongs to different user")
 	}
 
-	// Update only provided fields
-	if req.Name != nil {
-		existing.Name = *req.Name
-	}
-	if req.Title != nil {
-		existing.Title = *req.Title
-	}
-	if req.Bio != nil {
-		existing.Bio = *req.Bio
-	}
-	if req.Email != nil {
-		existing.Email = *req.Email
-	}
-	if req.Phone != nil {
-		existing.Phone = *req.Phone
-	}
-	if req.Location != nil {
-		existing.Location = *req.Location
-	}
-	if req.Website != nil {
-		existing.Website = *req.Website
-	}
-	if req.LinkedIn != nil {
-		existing.LinkedIn = *req.LinkedIn
-	}
-	if req.GitHub != nil {
-		existing.GitHub = *req.GitHub
-	}
-	if req.Experience != nil {
-		existing.Experience = *req.Experience
-	}
-	if req.Education != nil {
-		existing.Education = *req.Education
-	}
-	if req.Projects != nil {
-		existing.Projects = *req.Projects
-	}
-	if req.Skills != nil {
-		existing.Skills = *req.Skills
-	}
-	if req.Template != nil {
-		existing.Template = *req.Template
-	}
+	wasPublic := existing.IsPublic
+	applyPortfolioUpdate(existing, req)
+
 	if req.IsPublic != nil {
+		if *req.IsPublic && !existing.IsPublic {
+			if err := u.checkCanPublish(ctx, userID); err != nil {
+				return nil, err
+			}
+		}
 		existing.IsPublic = *req.IsPublic
 	}
 
@@ -154,6 +136,10 @@ func (u *portfolioUsecase) UpdatePortfolio(ctx context.Context, id string, req *
 		return nil, fmt.Errorf("failed to update portfolio: %w", err)
 	}
 
+	if existing.IsPublic != wasPublic {
+		u.audit.record(ctx, client, portfolioVisibilityEvent(existing, ""))
+	}
+
 	return existing, nil
 }
 
@@ -287,6 +273,73 @@ func (u *portfolioUsecase) EnhanceWithAI(ctx context.Context, req *entities.AIEn
 	return portfolio, nil
 }
 
+func (u *portfolioUsecase) checkCanPublish(ctx context.Context, userID string) error {
+	if !u.requireVerifiedToPublish {
+		return nil
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := u.userRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsVerified {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+// applyPortfolioUpdate copies the provided fields, except visibility, onto the portfolio.
+func applyPortfolioUpdate(existing *entities.Portfolio, req *entities.UpdatePortfolioRequest) {
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Title != nil {
+		existing.Title = *req.Title
+	}
+	if req.Bio != nil {
+		existing.Bio = *req.Bio
+	}
+	if req.Email != nil {
+		existing.Email = *req.Email
+	}
+	if req.Phone != nil {
+		existing.Phone = *req.Phone
+	}
+	if req.Location != nil {
+		existing.Location = *req.Location
+	}
+	if req.Website != nil {
+		existing.Website = *req.Website
+	}
+	if req.LinkedIn != nil {
+		existing.LinkedIn = *req.LinkedIn
+	}
+	if req.GitHub != nil {
+		existing.GitHub = *req.GitHub
+	}
+	if req.Experience != nil {
+		existing.Experience = *req.Experience
+	}
+	if req.Education != nil {
+		existing.Education = *req.Education
+	}
+	if req.Projects != nil {
+		existing.Projects = *req.Projects
+	}
+	if req.Skills != nil {
+		existing.Skills = *req.Skills
+	}
+	if req.Template != nil {
+		existing.Template = *req.Template
+	}
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
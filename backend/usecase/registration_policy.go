@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"devfolio-backend/domain/entities"
+	"devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/config"
+)
+
+// ErrInviteRequired is returned when an account would be created in invite-only
+// mode without a usable invite code.
+var ErrInviteRequired = errors.New("registration is by invitation only, a valid invite code is required")
+
+// ErrEmailDomainNotAllowed is returned in domain mode for addresses outside the
+// allowed domains.
+var ErrEmailDomainNotAllowed = errors.New("only addresses at approved email domains can be used")
+
+// ErrDisposableEmail is returned for addresses at a blocklisted throwaway
+// email domain.
+var ErrDisposableEmail = errors.New("disposable email addresses cannot be used")
+
+// registrationPolicy decides whether a new account may be created. It is only
+// consulted for sign-ups and email changes; existing accounts can always sign in.
+type registrationPolicy struct {
+	inviteRepo     repositories.InviteRepository
+	mode           string
+	allowedDomains map[string]bool
+	// disposableDomains also blocks their subdomains
+	disposableDomains map[string]bool
+}
+
+func newRegistrationPolicy(inviteRepo repositories.InviteRepository, cfg *config.Config) (*registrationPolicy, error) {
+	policy := &registrationPolicy{
+		inviteRepo:     inviteRepo,
+		mode:           strings.ToLower(strings.TrimSpace(cfg.Registration.Mode)),
+		allowedDomains: make(map[string]bool),
+	}
+
+	switch policy.mode {
+	case entities.RegistrationOpen, entities.RegistrationInvite:
+	case entities.RegistrationDomain:
+		for _, domain := range strings.Split(cfg.Registration.AllowedDomains, ",") {
+			domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+			if domain != "" {
+				policy.allowedDomains[domain] = true
+			}
+		}
+		if len(policy.allowedDomains) == 0 {
+			return nil, fmt.Errorf("registration mode domain needs at least one allowed domain")
+		}
+	default:
+		return nil, fmt.Errorf("unknown registration mode %q", cfg.Registration.Mode)
+	}
+
+	if cfg.Registration.DisposableDomainsFile != "" {
+		domains, err := loadDomainList(cfg.Registration.DisposableDomainsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load disposable email domains: %w", err)
+		}
+		policy.disposableDomains = domains
+	}
+
+	return policy, nil
+}
+
+// loadDomainList reads one domain per line. Blank lines and lines starting
+// with # are skipped.
+func loadDomainList(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	domains := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.TrimPrefix(line, "@")] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return domains, nil
+}
+
+// isDisposable reports whether the domain or one of its parents is blocklisted.
+func (p *registrationPolicy) isDisposable(domain string) bool {
+	for domain != "" {
+		if p.disposableDomains[domain] {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// admit checks that an account may be created for email. In invite-only mode
+// it takes one use of the invite, so concurrent sign-ups cannot overuse it, and
+// returns the invite. If the account is not created after all, the caller must
+// hand the invite to release.
+func (p *registrationPolicy) admit(ctx context.Context, email, inviteCode string) (*entities.Invite, error) {
+	if err := p.checkAddress(email); err != nil {
+		return nil, err
+	}
+
+	if p.mode == entities.RegistrationInvite {
+		inviteCode = strings.TrimSpace(inviteCode)
+		if inviteCode == "" {
+			return nil, ErrInviteRequired
+		}
+
+		invite, err := p.inviteRepo.GetByCodeHash(ctx, auth.HashToken(inviteCode))
+		if err != nil || !invite.IsUsable() {
+			return nil, ErrInviteRequired
+		}
+		if invite.Email != "" && entities.CanonicalEmail(invite.Email) != entities.CanonicalEmail(email) {
+			return nil, ErrInviteRequired
+		}
+
+		if err := p.inviteRepo.Redeem(ctx, invite.ID); err != nil {
+			return nil, ErrInviteRequired
+		}
+		return invite, nil
+	}
+
+	return nil, nil
+}
+
+// checkAddress applies the disposable domain blocklist and, in domain mode,
+// the allowed domains. Email changes go through it too, so an account cannot
+// move to an address it could not have registered with.
+func (p *registrationPolicy) checkAddress(email string) error {
+	domain := entities.EmailDomain(email)
+	if p.isDisposable(domain) {
+		return ErrDisposableEmail
+	}
+	if p.mode == entities.RegistrationDomain && !p.allowedDomains[domain] {
+		return ErrEmailDomainNotAllowed
+	}
+
+	return nil
+}
+
+// release gives back the invite use admit took. The invite may be nil.
+func (p *registrationPolicy) release(ctx context.Context, invite *entities.Invite) {
+	if invite == nil {
+		return
+	}
+	if err := p.inviteRepo.Release(ctx, invite.ID); err != nil {
+		fmt.Printf("Failed to release invite %s: %v\n", invite.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"devfolio-backend/domain/entities"
+	domainrepo "devfolio-backend/domain/repositories"
+	"devfolio-backend/infrastructure/auth"
+	"devfolio-backend/infrastructure/config"
+)
+
+func newInviteOnlyAuth(t *testing.T) *testAuth {
+	t.Helper()
+	return newTestAuth(t, func(cfg *config.Config) { cfg.Registration.Mode = entities.RegistrationInvite })
+}
+
+// createInvite stores a single-use invite and returns its code.
+func (ta *testAuth) createInvite(t *testing.T) (*entities.Invite, string) {
+	t.Helper()
+
+	code, err := auth.GenerateRandomToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invite := &entities.Invite{CodeHash: auth.HashToken(code), MaxUses: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := ta.inviteRepo.Create(context.Background(), invite); err != nil {
+		t.Fatal(err)
+	}
+	return invite, code
+}
+
+func (ta *testAuth) inviteUses(t *testing.T, invite *entities.Invite) int {
+	t.Helper()
+
+	stored, err := ta.inviteRepo.GetByCodeHash(context.Background(), invite.CodeHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored.Uses
+}
+
+func registerRequest(email, inviteCode string) *entities.RegisterRequest {
+	return &entities.RegisterRequest{
+		Email:      email,
+		Password:   "a long enough passphrase",
+		FirstName:  "New",
+		LastName:   "Member",
+		InviteCode: inviteCode,
+	}
+}
+
+func TestRegisterChecksPolicyBeforeEmail(t *testing.T) {
+	ctx := context.Background()
+	ta := newInviteOnlyAuth(t)
+	ta.createUser(t, "taken@example.com")
+
+	// Without an invite, taken and free addresses get the same answer
+	for _, email := range []string{"taken@example.com", "free@example.com"} {
+		if _, _, err := ta.Register(ctx, registerRequest(email, ""), &entities.ClientInfo{}); !errors.Is(err, ErrInviteRequired) {
+			t.Errorf("Register(%s) error = %v, want ErrInviteRequired", email, err)
+		}
+	}
+}
+
+func TestRegisterKeepsInviteWhenEmailIsTaken(t *testing.T) {
+	ctx := context.Background()
+	ta := newInviteOnlyAuth(t)
+	ta.createUser(t, "taken@example.com")
+	invite, code := ta.createInvite(t)
+
+	if _, _, err := ta.Register(ctx, registerRequest("taken@example.com", code), &entities.ClientInfo{}); err == nil {
+		t.Fatal("Register() with a taken address succeeded")
+	}
+	if uses := ta.inviteUses(t, invite); uses != 0 {
+		t.Fatalf("invite uses = %d after a failed registration, want 0", uses)
+	}
+
+	if _, _, err := ta.Register(ctx, registerRequest("free@example.com", code), &entities.ClientInfo{}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if uses := ta.inviteUses(t, invite); uses != 1 {
+		t.Fatalf("invite uses = %d, want 1", uses)
+	}
+}
+
+// failingCreate is a user repository whose inserts fail.
+type failingCreate struct {
+	domainrepo.UserRepository
+}
+
+func (failingCreate) Create(context.Context, *entities.User) error {
+	return errors.New("insert failed")
+}
+
+func TestFailedSignUpReleasesInvite(t *testing.T) {
+	ctx := context.Background()
+
+	signUps := map[string]func(t *testing.T, ta *testAuth, code string) error{
+		"password": func(t *testing.T, ta *testAuth, code string) error {
+			_, _, err := ta.Register(ctx, registerRequest("new@example.com", code), &entities.ClientInfo{})
+			return err
+		},
+		"provider": func(t *testing.T, ta *testAuth, code string) error {
+			profile := &entities.ExternalProfile{Provider: "github", Subject: "7", Email: "new@example.com", EmailVerified: true}
+			_, _, err := ta.LoginWithProvider(ctx, profile, code, &entities.ClientInfo{})
+			return err
+		},
+		"magic link": func(t *testing.T, ta *testAuth, code string) error {
+			_, _, err := ta.LoginWithMagicLink(ctx, ta.magicLinkToken(t, "new@example.com"), code, &entities.ClientInfo{})
+			return err
+		},
+	}
+
+	for name, signUp := range signUps {
+		t.Run(name, func(t *testing.T) {
+			ta := newInviteOnlyAuth(t)
+			ta.authUsecase.userRepo = failingCreate{ta.userRepo}
+			invite, code := ta.createInvite(t)
+
+			if err := signUp(t, ta, code); err == nil {
+				t.Fatal("sign-up succeeded although the insert failed")
+			}
+			if uses := ta.inviteUses(t, invite); uses != 0 {
+				t.Fatalf("invite uses = %d after a failed sign-up, want 0", uses)
+			}
+		})
+	}
+}
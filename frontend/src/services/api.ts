@@ -271,9 +271,16 @@ class ApiService {
   }
 
   async refreshToken(): Promise<{ access_token: string }> {
+    // The refresh cookie is only accepted together with its CSRF token
+    const csrfResponse = await fetch(`${API_BASE_URL}/auth/csrf`, {
+      credentials: "include",
+    });
+    const { csrf_token } = await this.handleResponse<{ csrf_token: string }>(csrfResponse);
+
     const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
       method: "POST",
       credentials: "include",
+      headers: { "X-CSRF-Token": csrf_token },
     });
 
     const result = await this.handleResponse<{ access_token: string }>(response);